@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.ngs.io/dropbox-mcp-server/internal/auth"
+	"go.ngs.io/dropbox-mcp-server/internal/config"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// checkTimeout bounds the Dropbox calls made by --check.
+const checkTimeout = 30 * time.Second
+
+// runCheck verifies the configuration and credentials without starting a
+// transport, writing a summary to w. It never prompts or opens a browser. It
+// returns false when any check fails.
+func runCheck(w io.Writer) bool {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+
+	if _, err := os.Stat(configPath); err != nil {
+		fmt.Fprintf(w, "Config:  %s (not found, using environment only)\n", configPath)
+	} else {
+		fmt.Fprintf(w, "Config:  %s\n", configPath)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+
+	if err := config.ValidatePathRoot(cfg.PathRoot); err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+	if err := config.ValidateBasePath(cfg.BasePath); err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+	if _, err := config.ParseProxyURL(cfg.ProxyURL); err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+	if err := config.ValidateTempDir(cfg.TempDir); err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+	if err := auth.ValidateRedirectHost(cfg.RedirectHost); err != nil {
+		fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+		return false
+	}
+	if cfg.RedirectURI != "" {
+		if _, err := auth.ParseRedirectURI(cfg.RedirectURI); err != nil {
+			fmt.Fprintf(w, "Config:  ERROR %v\n", err)
+			return false
+		}
+	}
+
+	if cfg.Token() == "" {
+		fmt.Fprintln(w, "Token:   ERROR not authenticated, run dropbox_auth from your MCP client first")
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	// NewClient refreshes an expired token when a refresh token is available
+	client, err := dropbox.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(w, "Token:   ERROR %v\n", err)
+		return false
+	}
+
+	if err := dropbox.ValidateToken(cfg); err != nil {
+		fmt.Fprintf(w, "Token:   ERROR %v\n", err)
+		return false
+	}
+	if cfg.HasEnvAccessToken() {
+		fmt.Fprintf(w, "Token:   OK (from %s)\n", config.EnvAccessToken)
+	} else if cfg.ExpiresAt.IsZero() {
+		fmt.Fprintln(w, "Token:   OK")
+	} else {
+		fmt.Fprintf(w, "Token:   OK (expires %s)\n", cfg.ExpiresAt.Local().Format(time.RFC3339))
+	}
+
+	account, err := client.GetCurrentAccount()
+	if err != nil {
+		fmt.Fprintf(w, "Account: ERROR %v\n", err)
+		return false
+	}
+	name := ""
+	if account.Name != nil {
+		name = account.Name.DisplayName
+	}
+	fmt.Fprintf(w, "Account: OK %s <%s>\n", name, account.Email)
+
+	fmt.Fprintln(w, "\nAll checks passed")
+	return true
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ngs.io/dropbox-mcp-server/internal/handlers"
+)
+
+// serveHTTP serves the MCP Streamable HTTP transport on addr. Each POST to
+// /mcp carries one JSON-RPC message or batch and receives the JSON response in
+// the body. Browser requests are only accepted from loopback origins and from
+// allowedOrigins. When ctx is canceled the server stops accepting connections
+// and waits up to shutdownTimeout for in-flight requests to finish.
+func serveHTTP(ctx context.Context, handler *handlers.Handler, addr string, allowedOrigins []string, shutdownTimeout time.Duration) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newHTTPHandler(handler, allowedOrigins),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Listening on http://%s/mcp\n", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+
+	return nil
+}
+
+// newHTTPHandler returns the handler serving /mcp.
+func newHTTPHandler(handler *handlers.Handler, allowedOrigins []string) http.Handler {
+	// The handler keeps shared token state, so requests from multiple
+	// clients are dispatched one at a time.
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		// A web page can reach a local server through DNS rebinding, so
+		// requests sent by browsers from other origins are refused
+		if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(origin, allowedOrigins) {
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		output := handleMessage(handler, body, nil)
+		mu.Unlock()
+
+		// Notifications produce no response body
+		if output == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write response: %v\n", err)
+		}
+	})
+
+	return mux
+}
+
+// splitOrigins splits the comma-separated --allowed-origins value.
+func splitOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// originAllowed reports whether a request with the Origin header origin may
+// be served: the origin is a loopback address or is listed in allowed.
+func originAllowed(origin string, allowed []string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/browser"
@@ -21,13 +25,31 @@ const (
 type OAuthConfig struct {
 	ClientID     string
 	ClientSecret string
-	RedirectURI  string
+	// RedirectURI, if set, is the redirect URI registered for the app, such
+	// as http://localhost:53682/callback. The callback server listens on its
+	// port instead of a random one.
+	RedirectURI string
+	// RedirectHost is the loopback host of the redirect URI when RedirectURI
+	// is not set: "localhost" (the default) or "127.0.0.1". It must match
+	// the redirect URI registered for the app exactly.
+	RedirectHost string
+	// HTTPClient, if set, is used for token requests instead of the
+	// default client.
+	HTTPClient *http.Client
 }
 
 type AuthResult struct {
 	AccessToken  string
 	RefreshToken string
 	ExpiresAt    time.Time
+	// Scopes lists the scopes granted to the token, if Dropbox reported them
+	Scopes []string
+}
+
+// grantedScopes returns the space-separated scope field of a token response.
+func grantedScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
 }
 
 func generateState() (string, error) {
@@ -38,31 +60,95 @@ func generateState() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// defaultCallbackPath is the path of the callback server when no redirect
+// URI is configured.
+const defaultCallbackPath = "/callback"
+
+// Loopback hosts for redirect URIs on a random port.
+const (
+	RedirectHostLocalhost = "localhost"
+	RedirectHostLoopback  = "127.0.0.1"
+)
+
+// ValidateRedirectHost reports whether value is a supported redirect host.
+func ValidateRedirectHost(value string) error {
+	switch value {
+	case "", RedirectHostLocalhost, RedirectHostLoopback:
+		return nil
+	}
+	return fmt.Errorf("invalid redirect host %q: must be %q or %q", value, RedirectHostLocalhost, RedirectHostLoopback)
+}
+
+// ParseRedirectURI parses a redirect URI for the local callback server. It
+// must be an http URL on a loopback host with an explicit port, because the
+// server listens on that port.
+func ParseRedirectURI(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URI: %w", err)
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("invalid redirect URI %q: scheme must be http", value)
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+	default:
+		return nil, fmt.Errorf("invalid redirect URI %q: host must be localhost, 127.0.0.1 or [::1]", value)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("invalid redirect URI %q: a port is required", value)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// callbackListener starts listening for the OAuth callback and returns the
+// redirect URI that points at the listener. Without a configured redirect
+// URI, a random port on the redirect host is used.
+func callbackListener(config OAuthConfig) (net.Listener, *url.URL, error) {
+	if config.RedirectURI == "" {
+		if err := ValidateRedirectHost(config.RedirectHost); err != nil {
+			return nil, nil, err
+		}
+		host := config.RedirectHost
+		if host == "" {
+			host = RedirectHostLocalhost
+		}
+
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start local server: %w", err)
+		}
+		port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+		return listener, &url.URL{Scheme: "http", Host: net.JoinHostPort(host, port), Path: defaultCallbackPath}, nil
+	}
+
+	redirectURI, err := ParseRedirectURI(config.RedirectURI)
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := net.Listen("tcp", redirectURI.Host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on %s for the redirect URI: %w", redirectURI.Host, err)
+	}
+	return listener, redirectURI, nil
+}
+
 func StartOAuthFlow(config OAuthConfig) (*AuthResult, error) {
 	state, err := generateState()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate state: %w", err)
 	}
 
-	listener, err := net.Listen("tcp", "localhost:0")
+	listener, callback, err := callbackListener(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start local server: %w", err)
+		return nil, err
 	}
 	defer listener.Close()
 
-	port := listener.Addr().(*net.TCPAddr).Port
-	redirectURI := fmt.Sprintf("http://localhost:%d/callback", port)
-
-	oauth2Config := &oauth2.Config{
-		ClientID:     config.ClientID,
-		ClientSecret: config.ClientSecret,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  AuthorizeURL,
-			TokenURL: TokenURL,
-		},
-		RedirectURL: redirectURI,
-		Scopes:      []string{},
-	}
+	oauth2Config := newOAuth2Config(config, callback.String())
 
 	authURL := oauth2Config.AuthCodeURL(state,
 		oauth2.SetAuthURLParam("token_access_type", "offline"),
@@ -74,7 +160,7 @@ func StartOAuthFlow(config OAuthConfig) (*AuthResult, error) {
 	server := &http.Server{
 		ReadHeaderTimeout: 10 * time.Second,
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path != "/callback" {
+			if r.URL.Path != callback.Path {
 				http.NotFound(w, r)
 				return
 			}
@@ -97,21 +183,14 @@ func StartOAuthFlow(config OAuthConfig) (*AuthResult, error) {
 				return
 			}
 
-			ctx := context.Background()
-			token, err := oauth2Config.Exchange(ctx, code)
+			token, err := oauth2Config.Exchange(tokenContext(config), code)
 			if err != nil {
 				errorChan <- fmt.Errorf("token exchange failed: %w", err)
 				http.Error(w, "Token exchange failed", http.StatusInternalServerError)
 				return
 			}
 
-			result := &AuthResult{
-				AccessToken:  token.AccessToken,
-				RefreshToken: token.RefreshToken,
-				ExpiresAt:    token.Expiry,
-			}
-
-			resultChan <- result
+			resultChan <- tokenResult(token)
 
 			w.Header().Set("Content-Type", "text/html")
 			fmt.Fprintf(w, `<!DOCTYPE html>
@@ -155,34 +234,76 @@ func StartOAuthFlow(config OAuthConfig) (*AuthResult, error) {
 	}
 }
 
+// ExchangeCode exchanges an authorization code obtained out of band, for
+// example by a CI pipeline, for tokens without starting a local server or
+// opening a browser. redirectURI must be the redirect URI the code was
+// requested with, or empty if the code was requested without one.
+func ExchangeCode(config OAuthConfig, code, redirectURI string) (*AuthResult, error) {
+	if redirectURI != "" {
+		if u, err := url.Parse(redirectURI); err != nil || !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("invalid redirect URI %q: must be an absolute URL", redirectURI)
+		}
+	}
+
+	token, err := newOAuth2Config(config, redirectURI).Exchange(tokenContext(config), code)
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && strings.Contains(retrieveErr.ErrorDescription, "redirect_uri") {
+			return nil, fmt.Errorf("token exchange failed: redirect URI %q does not match the one the code was requested with: %w", redirectURI, err)
+		}
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	return tokenResult(token), nil
+}
+
 func RefreshToken(config OAuthConfig, refreshToken string) (*AuthResult, error) {
-	oauth2Config := &oauth2.Config{
+	token := &oauth2.Token{
+		RefreshToken: refreshToken,
+	}
+
+	newToken, err := newOAuth2Config(config, "").TokenSource(tokenContext(config), token).Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return tokenResult(newToken), nil
+}
+
+func newOAuth2Config(config OAuthConfig, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
 		ClientID:     config.ClientID,
 		ClientSecret: config.ClientSecret,
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  AuthorizeURL,
 			TokenURL: TokenURL,
 		},
+		RedirectURL: redirectURI,
 	}
+}
 
-	token := &oauth2.Token{
-		RefreshToken: refreshToken,
-	}
-
+// tokenContext returns the context for token requests, which carries the
+// configured HTTP client.
+func tokenContext(config OAuthConfig) context.Context {
 	ctx := context.Background()
-	newToken, err := oauth2Config.TokenSource(ctx, token).Token()
-	if err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	if config.HTTPClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, config.HTTPClient)
 	}
+	return ctx
+}
 
+func tokenResult(token *oauth2.Token) *AuthResult {
 	return &AuthResult{
-		AccessToken:  newToken.AccessToken,
-		RefreshToken: newToken.RefreshToken,
-		ExpiresAt:    newToken.Expiry,
-	}, nil
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.Expiry,
+		Scopes:       grantedScopes(token),
+	}
 }
 
-func ValidateToken(accessToken string) error {
+// ValidateToken checks accessToken with Dropbox using client, which carries
+// the proxy, User-Agent and timeout of the request.
+func ValidateToken(client *http.Client, accessToken string) error {
 	ctx := context.Background()
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.dropboxapi.com/2/check/user", http.NoBody)
 	if err != nil {
@@ -192,7 +313,6 @@ func ValidateToken(accessToken string) error {
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
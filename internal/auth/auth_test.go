@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseRedirectURI(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"http://localhost:53682/callback", true},
+		{"http://127.0.0.1:8080/oauth", true},
+		{"http://[::1]:8080/callback", true},
+		{"https://localhost:8080/callback", false},
+		{"http://example.com:8080/callback", false},
+		{"http://localhost/callback", false},
+		{"://", false},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRedirectURI(tt.value); (err == nil) != tt.valid {
+			t.Errorf("ParseRedirectURI(%q) error = %v, want valid %t", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestCallbackListenerUsesRedirectURIPort(t *testing.T) {
+	// Find a free port for the configured redirect URI
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(free.Addr().(*net.TCPAddr).Port)
+	free.Close()
+
+	redirectURI := "http://127.0.0.1:" + port + "/oauth"
+	listener, callback, err := callbackListener(OAuthConfig{RedirectURI: redirectURI})
+	if err != nil {
+		t.Fatalf("callbackListener() error = %v", err)
+	}
+	defer listener.Close()
+
+	if got := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port); got != port {
+		t.Errorf("listening on port %s, want %s", got, port)
+	}
+	if callback.String() != redirectURI {
+		t.Errorf("redirect URI = %s, want %s", callback, redirectURI)
+	}
+
+}
+
+func TestCallbackListenerRedirectHost(t *testing.T) {
+	for _, host := range []string{"", RedirectHostLocalhost, RedirectHostLoopback} {
+		listener, callback, err := callbackListener(OAuthConfig{RedirectHost: host})
+		if err != nil {
+			t.Fatalf("callbackListener(%q) error = %v", host, err)
+		}
+		listener.Close()
+
+		want := host
+		if want == "" {
+			want = RedirectHostLocalhost
+		}
+		if callback.Hostname() != want || callback.Path != defaultCallbackPath {
+			t.Errorf("callbackListener(%q) redirect URI = %s, want a %s callback", host, callback, want)
+		}
+	}
+
+	if _, _, err := callbackListener(OAuthConfig{RedirectHost: "example.com"}); err == nil {
+		t.Error("callbackListener() with a non-loopback host succeeded, want an error")
+	}
+}
+
+// redirectTransport sends every request to a test server.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestExchangeCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("redirect_uri") != "http://localhost:8080/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"redirect_uri mismatch"}`))
+			return
+		}
+		if r.Form.Get("code") != "abc" || r.Form.Get("grant_type") != "authorization_code" {
+			t.Errorf("form = %v, want an authorization_code grant for abc", r.Form)
+		}
+		w.Write([]byte(`{"access_token":"sl.token","refresh_token":"refresh","expires_in":14400,"scope":"files.content.read"}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	config := OAuthConfig{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		HTTPClient:   &http.Client{Transport: redirectTransport{target}},
+	}
+
+	result, err := ExchangeCode(config, "abc", "http://localhost:8080/callback")
+	if err != nil {
+		t.Fatalf("ExchangeCode() error = %v", err)
+	}
+	if result.AccessToken != "sl.token" || result.RefreshToken != "refresh" || len(result.Scopes) != 1 {
+		t.Errorf("ExchangeCode() = %+v, want the issued tokens", result)
+	}
+
+	_, err = ExchangeCode(config, "abc", "http://localhost:9090/callback")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("ExchangeCode() with another redirect URI error = %v, want a mismatch", err)
+	}
+	if _, err := ExchangeCode(config, "abc", "/callback"); err == nil {
+		t.Error("ExchangeCode() with a relative redirect URI succeeded, want an error")
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// WebhookSignatureHeader is the header carrying the signature of a webhook
+// notification.
+const WebhookSignatureHeader = "X-Dropbox-Signature"
+
+// VerifyWebhookSignature reports whether signature, the value of the
+// X-Dropbox-Signature header, is the hex-encoded HMAC-SHA256 of body keyed
+// with the app secret. The comparison takes constant time.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(got) != sha256.Size {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// HandleWebhookChallenge answers the GET request Dropbox sends to verify a
+// webhook URL by echoing its challenge parameter. It reports whether r was
+// such a request, so that a receiver can handle notifications otherwise.
+func HandleWebhookChallenge(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	challenge := r.URL.Query().Get("challenge")
+	if challenge == "" {
+		return false
+	}
+
+	// Dropbox requires the challenge to be echoed verbatim as plain text
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write([]byte(challenge))
+	return true
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"list_folder": {"accounts": ["dbid:AAH4f99T0taONIb-OurWxbNQ6ywGRopQngc"]}, "delta": {"users": [12345678]}}`)
+	const signature = "4c5c4f66f44d1c4caf09bf8bb533cce0bfbf09ea119d0f1945e1a38bd6c54670"
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", "app-secret", body, signature, true},
+		{"well-known vector", "key", []byte("The quick brown fox jumps over the lazy dog"),
+			"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", true},
+		{"uppercase hex", "app-secret", body, "4C5C4F66F44D1C4CAF09BF8BB533CCE0BFBF09EA119D0F1945E1A38BD6C54670", true},
+		{"wrong secret", "other-secret", body, signature, false},
+		{"tampered body", "app-secret", append([]byte{' '}, body...), signature, false},
+		{"truncated signature", "app-secret", body, signature[:32], false},
+		{"not hex", "app-secret", body, "not-a-signature", false},
+		{"missing", "app-secret", body, "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyWebhookSignature(tt.secret, tt.body, tt.signature); got != tt.want {
+			t.Errorf("%s: VerifyWebhookSignature() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleWebhookChallenge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if !HandleWebhookChallenge(rec, httptest.NewRequest(http.MethodGet, "/webhook?challenge=abc123", http.NoBody)) {
+		t.Fatal("HandleWebhookChallenge() = false for a challenge request")
+	}
+	if rec.Body.String() != "abc123" || rec.Header().Get("Content-Type") != "text/plain" ||
+		rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("response = %q with headers %v, want the challenge as plain text", rec.Body.String(), rec.Header())
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/webhook?challenge=abc123", http.NoBody),
+		httptest.NewRequest(http.MethodGet, "/webhook", http.NoBody),
+	} {
+		rec := httptest.NewRecorder()
+		if HandleWebhookChallenge(rec, req) {
+			t.Errorf("HandleWebhookChallenge() = true for %s %s", req.Method, req.URL)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s %s: wrote %q, want nothing", req.Method, req.URL, rec.Body.String())
+		}
+	}
+}
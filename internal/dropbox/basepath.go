@@ -0,0 +1,135 @@
+package dropbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
+)
+
+// scopePath validates a path argument and maps it below the configured base
+// path. With a base path only display paths are accepted, since IDs and
+// namespace paths could refer to items outside of it.
+func (c *Client) scopePath(path string) (string, error) {
+	kind, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	if c.basePath == "" {
+		return path, nil
+	}
+
+	if kind != PathKindDisplay {
+		return "", fmt.Errorf("invalid path %q: only /-separated paths are allowed when a base path is configured", path)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid path %q: .. is not allowed", path)
+		}
+	}
+
+	return c.basePath + strings.TrimSuffix(path, "/"), nil
+}
+
+// unscopePath strips the base path from a path returned by Dropbox.
+func (c *Client) unscopePath(path string) string {
+	n := len(c.basePath)
+	if n == 0 || len(path) < n || !strings.EqualFold(path[:n], c.basePath) {
+		return path
+	}
+	if len(path) == n {
+		return "/"
+	}
+	if path[n] != '/' {
+		return path
+	}
+	return path[n:]
+}
+
+// inBasePath reports whether a lowercased path returned by Dropbox is inside
+// the base path.
+func (c *Client) inBasePath(pathLower string) bool {
+	if c.basePath == "" {
+		return true
+	}
+	base := strings.ToLower(c.basePath)
+	return pathLower == base || strings.HasPrefix(pathLower, base+"/")
+}
+
+// unscopeEntries drops the entries of a listing that are outside the base
+// path and strips the base path from the rest. Listings continued from a
+// cursor supplied by the caller can cover any folder, so their entries are
+// checked rather than trusted.
+func (c *Client) unscopeEntries(entries []files.IsMetadata) []files.IsMetadata {
+	if c.basePath == "" {
+		return entries
+	}
+
+	scoped := entries[:0]
+	for _, entry := range entries {
+		if !c.inBasePath(metadataPathLower(entry)) {
+			continue
+		}
+		c.unscopeMetadata(entry)
+		scoped = append(scoped, entry)
+	}
+	return scoped
+}
+
+// metadataPathLower returns the lowercased path of a file, folder or deleted
+// item.
+func metadataPathLower(metadata files.IsMetadata) string {
+	switch v := metadata.(type) {
+	case *files.FileMetadata:
+		return v.PathLower
+	case *files.FolderMetadata:
+		return v.PathLower
+	case *files.DeletedMetadata:
+		return v.PathLower
+	}
+	return ""
+}
+
+// unscopeMetadata strips the base path from the paths in metadata in place.
+func (c *Client) unscopeMetadata(metadata files.IsMetadata) {
+	if c.basePath == "" {
+		return
+	}
+
+	var m *files.Metadata
+	switch v := metadata.(type) {
+	case *files.FileMetadata:
+		m = &v.Metadata
+	case *files.FolderMetadata:
+		m = &v.Metadata
+	case *files.DeletedMetadata:
+		m = &v.Metadata
+	default:
+		return
+	}
+	m.PathDisplay = c.unscopePath(m.PathDisplay)
+	m.PathLower = c.unscopePath(m.PathLower)
+}
+
+// unscopeLink strips the base path from the path of a shared link in place.
+func (c *Client) unscopeLink(link sharing.IsSharedLinkMetadata) {
+	switch l := link.(type) {
+	case *sharing.FileLinkMetadata:
+		l.PathLower = c.unscopePath(l.PathLower)
+	case *sharing.FolderLinkMetadata:
+		l.PathLower = c.unscopePath(l.PathLower)
+	}
+}
+
+// linkPathLower returns the lowercased path of the item a shared link points
+// to, which is empty when the link's item is not in the user's Dropbox.
+func linkPathLower(link sharing.IsSharedLinkMetadata) string {
+	switch l := link.(type) {
+	case *sharing.FileLinkMetadata:
+		return l.PathLower
+	case *sharing.FolderLinkMetadata:
+		return l.PathLower
+	}
+	return ""
+}
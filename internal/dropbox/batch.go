@@ -0,0 +1,438 @@
+package dropbox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/async"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// Batch job types accepted by CheckBatchJob.
+const (
+	JobTypeMove   = "move"
+	JobTypeCopy   = "copy"
+	JobTypeDelete = "delete"
+)
+
+// Batch job states reported in BatchJobStatus.
+const (
+	JobInProgress = "in_progress"
+	JobComplete   = "complete"
+	JobFailed     = "failed"
+)
+
+// BatchJobStatus is the state of an asynchronous batch job.
+type BatchJobStatus struct {
+	// Status is JobInProgress, JobComplete or JobFailed.
+	Status string
+	// AsyncJobID identifies the job while it runs in the background. It is
+	// empty when Dropbox completed a batch immediately.
+	AsyncJobID string
+	// Entries holds one result per batch entry, in request order, once the
+	// job is complete.
+	Entries []BatchEntryResult
+	// Error describes why the whole job failed.
+	Error string
+	// Deduplicated holds the copies of a deduplicated batch copy that were
+	// saved from a copy reference instead of being part of the job.
+	Deduplicated []DedupCopy
+}
+
+// DedupCopy is the outcome of a copy that was saved from a copy reference to
+// a file with the same content, rather than copied from its own source.
+type DedupCopy struct {
+	FromPath string
+	ToPath   string
+	// ReferencePath is the source the copy reference was taken from.
+	ReferencePath string
+	// Metadata is the new copy on success.
+	Metadata files.IsMetadata
+	// Error is the Dropbox error tag on failure, such as "path/conflict".
+	Error string
+}
+
+// BatchEntryResult is the outcome of a single entry of a batch job.
+type BatchEntryResult struct {
+	// Metadata is the moved, copied or deleted item on success.
+	Metadata files.IsMetadata
+	// Error is the Dropbox error tag on failure, such as "to/conflict".
+	Error string
+}
+
+// RelocationEntry is a single source and destination of a batch move or copy.
+type RelocationEntry struct {
+	FromPath string
+	ToPath   string
+}
+
+// MoveBatch starts moving entries in a single batch job. Dropbox may finish
+// small batches right away; otherwise the returned status is JobInProgress
+// and carries the AsyncJobID to pass to CheckBatchJob.
+func (c *Client) MoveBatch(entries []RelocationEntry, autorename bool) (*BatchJobStatus, error) {
+	paths, err := c.relocationPaths(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := files.NewMoveBatchArg(paths)
+	arg.Autorename = autorename
+	arg.AllowOwnershipTransfer = false
+
+	res, err := c.filesClient.MoveBatchV2(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start move batch: %w", err)
+	}
+	return c.relocationLaunchStatus(res), nil
+}
+
+// CopyBatchOptions controls a batch copy.
+type CopyBatchOptions struct {
+	// Autorename renames copies whose destination already exists instead of
+	// failing them.
+	Autorename bool
+	// Dedup copies each distinct file content only once. Further entries
+	// whose source has the same content hash are saved from a copy reference
+	// and reported in BatchJobStatus.Deduplicated instead of Entries. Saving
+	// a copy reference cannot autorename, so those copies fail on a
+	// destination conflict regardless of Autorename.
+	Dedup bool
+}
+
+// CopyBatch starts copying entries in a single batch job, like MoveBatch.
+// With opts.Dedup, copies saved before the batch could be started are
+// reported in a JobFailed status rather than dropped with the error.
+func (c *Client) CopyBatch(entries []RelocationEntry, opts CopyBatchOptions) (*BatchJobStatus, error) {
+	// Nothing is copied unless every entry is valid
+	if _, err := c.relocationPaths(entries); err != nil {
+		return nil, err
+	}
+
+	var deduplicated []DedupCopy
+	if opts.Dedup {
+		entries, deduplicated = c.copyDeduplicated(entries)
+	}
+
+	status := &BatchJobStatus{Status: JobComplete}
+	if len(entries) > 0 {
+		paths, err := c.relocationPaths(entries)
+		if err != nil {
+			return nil, err
+		}
+
+		arg := files.NewRelocationBatchArgBase(paths)
+		arg.Autorename = opts.Autorename
+
+		res, err := c.filesClient.CopyBatchV2(arg)
+		if err != nil {
+			if len(deduplicated) == 0 {
+				return nil, fmt.Errorf("failed to start copy batch: %w", err)
+			}
+			status = &BatchJobStatus{Status: JobFailed, Error: fmt.Sprintf("failed to start copy batch: %v", err)}
+		} else {
+			status = c.relocationLaunchStatus(res)
+		}
+	}
+
+	status.Deduplicated = deduplicated
+	return status, nil
+}
+
+// copyDeduplicated saves every entry whose source file has the same content
+// as the source of an earlier entry from a copy reference to that earlier
+// source. It returns the entries that still need to be copied. Folders,
+// sources that cannot be looked up and entries whose copy reference cannot
+// be obtained are left to the batch, which reports their errors.
+func (c *Client) copyDeduplicated(entries []RelocationEntry) ([]RelocationEntry, []DedupCopy) {
+	hashes := map[string]string{}     // lowercased source path to content hash
+	origins := map[string]string{}    // content hash to the first source with it
+	references := map[string]string{} // origin source to its copy reference
+
+	var remaining []RelocationEntry
+	var deduplicated []DedupCopy
+	for _, entry := range entries {
+		key := strings.ToLower(entry.FromPath)
+		hash, ok := hashes[key]
+		if !ok {
+			if metadata, err := c.GetMetadata(entry.FromPath); err == nil {
+				if file, isFile := metadata.(*files.FileMetadata); isFile {
+					hash = file.ContentHash
+				}
+			}
+			hashes[key] = hash
+		}
+
+		origin, seen := origins[hash]
+		if hash == "" || !seen {
+			if hash != "" {
+				origins[hash] = entry.FromPath
+			}
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		reference, ok := references[origin]
+		if !ok {
+			// Without a reference the entry is copied as usual
+			reference, _ = c.getCopyReference(origin)
+			references[origin] = reference
+		}
+		if reference == "" {
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		result := DedupCopy{
+			FromPath:      entry.FromPath,
+			ToPath:        entry.ToPath,
+			ReferencePath: origin,
+		}
+		result.Metadata, result.Error = c.saveCopyReference(reference, entry.ToPath)
+		deduplicated = append(deduplicated, result)
+	}
+
+	return remaining, deduplicated
+}
+
+func (c *Client) getCopyReference(path string) (string, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := c.filesClient.CopyReferenceGet(files.NewGetCopyReferenceArg(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to get copy reference: %w", err)
+	}
+	return res.CopyReference, nil
+}
+
+// saveCopyReference saves the file a copy reference points to at path. It
+// returns the new file, or an error tag like the batch entries use.
+func (c *Client) saveCopyReference(reference, path string) (files.IsMetadata, string) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	res, err := c.filesClient.CopyReferenceSave(files.NewSaveCopyReferenceArg(reference, path))
+	if err != nil {
+		var saveErr files.CopyReferenceSaveAPIError
+		if errors.As(err, &saveErr) && saveErr.EndpointError != nil {
+			if saveErr.EndpointError.Path != nil {
+				return nil, saveErr.EndpointError.Tag + "/" + saveErr.EndpointError.Path.Tag
+			}
+			return nil, saveErr.EndpointError.Tag
+		}
+		return nil, err.Error()
+	}
+
+	c.unscopeMetadata(res.Metadata)
+	return res.Metadata, ""
+}
+
+// DeleteBatch starts deleting paths in a single batch job, like MoveBatch.
+func (c *Client) DeleteBatch(paths []string) (*BatchJobStatus, error) {
+	args := make([]*files.DeleteArg, 0, len(paths))
+	for _, path := range paths {
+		scoped, err := c.scopePath(path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, files.NewDeleteArg(scoped))
+	}
+
+	res, err := c.filesClient.DeleteBatch(files.NewDeleteBatchArg(args))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start delete batch: %w", err)
+	}
+
+	switch res.Tag {
+	case files.DeleteBatchLaunchAsyncJobId:
+		return &BatchJobStatus{Status: JobInProgress, AsyncJobID: res.AsyncJobId}, nil
+	case files.DeleteBatchLaunchComplete:
+		if res.Complete == nil {
+			return &BatchJobStatus{Status: JobComplete}, nil
+		}
+		return c.deleteEntries(res.Complete.Entries), nil
+	}
+
+	return &BatchJobStatus{Status: JobFailed, Error: res.Tag}, nil
+}
+
+// relocationPaths validates and scopes the entries of a move or copy batch.
+func (c *Client) relocationPaths(entries []RelocationEntry) ([]*files.RelocationPath, error) {
+	paths := make([]*files.RelocationPath, 0, len(entries))
+	for _, entry := range entries {
+		fromPath, err := c.scopePath(entry.FromPath)
+		if err != nil {
+			return nil, err
+		}
+		toPath, err := c.scopePath(entry.ToPath)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files.NewRelocationPath(fromPath, toPath))
+	}
+	return paths, nil
+}
+
+func (c *Client) relocationLaunchStatus(res *files.RelocationBatchV2Launch) *BatchJobStatus {
+	if res.Tag == files.RelocationBatchV2LaunchComplete && res.Complete != nil {
+		return c.relocationEntries(res.Complete.Entries)
+	}
+	return &BatchJobStatus{Status: JobInProgress, AsyncJobID: res.AsyncJobId}
+}
+
+// CheckBatchJob returns the status of a move, copy or delete batch job.
+func (c *Client) CheckBatchJob(jobType, asyncJobID string) (*BatchJobStatus, error) {
+	arg := async.NewPollArg(asyncJobID)
+
+	var status *BatchJobStatus
+	var err error
+	switch jobType {
+	case JobTypeMove:
+		var res *files.RelocationBatchV2JobStatus
+		if res, err = c.filesClient.MoveBatchCheckV2(arg); err == nil {
+			status = c.relocationJobStatus(res)
+		}
+	case JobTypeCopy:
+		var res *files.RelocationBatchV2JobStatus
+		if res, err = c.filesClient.CopyBatchCheckV2(arg); err == nil {
+			status = c.relocationJobStatus(res)
+		}
+	case JobTypeDelete:
+		var res *files.DeleteBatchJobStatus
+		if res, err = c.filesClient.DeleteBatchCheck(arg); err == nil {
+			status = c.deleteJobStatus(res)
+		}
+	default:
+		return nil, fmt.Errorf("invalid job type %q: must be %s, %s or %s", jobType, JobTypeMove, JobTypeCopy, JobTypeDelete)
+	}
+
+	if err != nil {
+		switch pollErrorTag(err) {
+		case async.PollErrorInvalidAsyncJobId:
+			return nil, fmt.Errorf("unknown %s job %s, it may have expired or belong to another job type", jobType, asyncJobID)
+		case async.PollErrorInternalError:
+			return &BatchJobStatus{Status: JobFailed, Error: "internal error"}, nil
+		}
+		return nil, fmt.Errorf("failed to check %s job: %w", jobType, err)
+	}
+	return status, nil
+}
+
+// pollErrorTag returns the tag of a batch check failure, if any.
+func pollErrorTag(err error) string {
+	var pollErr *async.PollError
+
+	var moveErr files.MoveBatchCheckV2APIError
+	var copyErr files.CopyBatchCheckV2APIError
+	var deleteErr files.DeleteBatchCheckAPIError
+	switch {
+	case errors.As(err, &moveErr):
+		pollErr = moveErr.EndpointError
+	case errors.As(err, &copyErr):
+		pollErr = copyErr.EndpointError
+	case errors.As(err, &deleteErr):
+		pollErr = deleteErr.EndpointError
+	}
+
+	if pollErr == nil {
+		return ""
+	}
+	return pollErr.Tag
+}
+
+func (c *Client) relocationJobStatus(res *files.RelocationBatchV2JobStatus) *BatchJobStatus {
+	if res.Tag != files.RelocationBatchV2JobStatusComplete || res.Complete == nil {
+		return &BatchJobStatus{Status: JobInProgress}
+	}
+	return c.relocationEntries(res.Complete.Entries)
+}
+
+// relocationEntries converts the entries of a completed move or copy batch.
+func (c *Client) relocationEntries(entries []*files.RelocationBatchResultEntry) *BatchJobStatus {
+	status := &BatchJobStatus{Status: JobComplete}
+	for _, entry := range entries {
+		var result BatchEntryResult
+		switch {
+		case entry.Success != nil:
+			c.unscopeMetadata(entry.Success)
+			result.Metadata = entry.Success
+		case entry.Failure != nil:
+			result.Error = entry.Failure.Tag
+			if entry.Failure.RelocationError != nil {
+				result.Error = relocationErrorTag(entry.Failure.RelocationError)
+			}
+		default:
+			result.Error = entry.Tag
+		}
+		status.Entries = append(status.Entries, result)
+	}
+	return status
+}
+
+func (c *Client) deleteJobStatus(res *files.DeleteBatchJobStatus) *BatchJobStatus {
+	switch res.Tag {
+	case files.DeleteBatchJobStatusComplete:
+	case files.DeleteBatchJobStatusFailed:
+		status := &BatchJobStatus{Status: JobFailed}
+		if res.Failed != nil {
+			status.Error = res.Failed.Tag
+		}
+		return status
+	default:
+		return &BatchJobStatus{Status: JobInProgress}
+	}
+
+	if res.Complete == nil {
+		return &BatchJobStatus{Status: JobComplete}
+	}
+	return c.deleteEntries(res.Complete.Entries)
+}
+
+// deleteEntries converts the entries of a completed delete batch.
+func (c *Client) deleteEntries(entries []*files.DeleteBatchResultEntry) *BatchJobStatus {
+	status := &BatchJobStatus{Status: JobComplete}
+	for _, entry := range entries {
+		var result BatchEntryResult
+		switch {
+		case entry.Success != nil:
+			c.unscopeMetadata(entry.Success.Metadata)
+			result.Metadata = entry.Success.Metadata
+		case entry.Failure != nil:
+			result.Error = deleteErrorTag(entry.Failure)
+		default:
+			result.Error = entry.Tag
+		}
+		status.Entries = append(status.Entries, result)
+	}
+	return status
+}
+
+// relocationErrorTag describes a relocation error by its tag and, for path
+// errors, the nested tag, such as "to/conflict".
+func relocationErrorTag(err *files.RelocationError) string {
+	switch {
+	case err.FromLookup != nil:
+		return err.Tag + "/" + err.FromLookup.Tag
+	case err.FromWrite != nil:
+		return err.Tag + "/" + err.FromWrite.Tag
+	case err.To != nil:
+		return err.Tag + "/" + err.To.Tag
+	}
+	return err.Tag
+}
+
+// deleteErrorTag describes a delete error like relocationErrorTag.
+func deleteErrorTag(err *files.DeleteError) string {
+	switch {
+	case err.PathLookup != nil:
+		return err.Tag + "/" + err.PathLookup.Tag
+	case err.PathWrite != nil:
+		return err.Tag + "/" + err.PathWrite.Tag
+	}
+	return err.Tag
+}
@@ -0,0 +1,121 @@
+package dropbox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for the circuit breaker in front of the Dropbox API.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrServiceUnavailable is returned without contacting Dropbox while the
+// circuit breaker is open after repeated failures.
+var ErrServiceUnavailable = errors.New("service unavailable")
+
+// circuitBreaker stops sending requests to Dropbox once threshold requests
+// in a row have failed, so that an outage fails calls fast instead of every
+// call waiting for its own timeout. After cooldown a single request is let
+// through to test whether Dropbox has recovered: it closes the breaker on
+// success and opens it for another cooldown on failure.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	// probing is set while the request testing recovery is in flight
+	probing bool
+}
+
+// newCircuitBreaker returns a breaker configured from the environment. A
+// cooldown of zero disables it.
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{
+		threshold: envInt("DROPBOX_MCP_BREAKER_THRESHOLD", defaultBreakerThreshold),
+		cooldown:  EnvDuration("DROPBOX_MCP_BREAKER_COOLDOWN", defaultBreakerCooldown),
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a request may be sent, and whether it is the request
+// testing recovery.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cooldown == 0 || b.failures < b.threshold {
+		return false, nil
+	}
+	if wait := b.openUntil.Sub(b.now()); wait > 0 || b.probing {
+		if wait < 0 {
+			wait = 0
+		}
+		return false, fmt.Errorf("%w: the last %d requests to Dropbox failed, not retrying for %s",
+			ErrServiceUnavailable, b.failures, wait.Round(time.Second))
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record counts the outcome of a request that allow let through.
+func (b *circuitBreaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// release ends a request that allow let through without counting it.
+func (b *circuitBreaker) release(probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+}
+
+// breakerTransport sends requests through a circuit breaker. Connection
+// errors and server errors count as failures. Other error responses, such as
+// a missing file or rate limiting, show that Dropbox is up.
+type breakerTransport struct {
+	breaker *circuitBreaker
+	base    http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	probe, err := t.breaker.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	// A canceled call says nothing about the health of Dropbox
+	if err != nil && req.Context().Err() != nil {
+		t.breaker.release(probe)
+		return resp, err
+	}
+	t.breaker.record(probe, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
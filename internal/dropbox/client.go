@@ -2,38 +2,51 @@ package dropbox
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox"
+	dbxauth "github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/auth"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/common"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/file_properties"
 	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/paper"
 	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/team"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/users"
 	"go.ngs.io/dropbox-mcp-server/internal/auth"
 	"go.ngs.io/dropbox-mcp-server/internal/config"
 )
 
 type Client struct {
-	filesClient   files.Client
-	sharingClient sharing.Client
-	config        *config.Config
+	// The SDK clients are interfaces, so tests substitute fakes that
+	// implement only the methods they exercise
+	filesClient      files.Client
+	sharingClient    sharing.Client
+	teamClient       team.Client
+	usersClient      users.Client
+	propertiesClient file_properties.Client
+	paperClient      paper.Client
+	config           *config.Config
+	// basePath is prepended to every path argument, without a trailing slash
+	basePath string
 }
 
-func NewClient(cfg *config.Config) (*Client, error) {
-	if cfg.NeedsRefresh() && cfg.RefreshToken != "" {
-		authConfig := auth.OAuthConfig{
-			ClientID:     cfg.ClientID,
-			ClientSecret: cfg.ClientSecret,
-		}
-		result, err := auth.RefreshToken(authConfig, cfg.RefreshToken)
-		if err != nil {
-			return nil, fmt.Errorf("failed to refresh token: %w", err)
-		}
-		cfg.UpdateTokens(result.AccessToken, result.RefreshToken, result.ExpiresAt)
-		if err := cfg.Save(); err != nil {
-			return nil, fmt.Errorf("failed to save updated config: %w", err)
+// NewClient creates a client for the configured account. All API requests
+// made through the client are bound to ctx, so canceling ctx or letting its
+// deadline pass aborts any call in progress.
+func NewClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	if cfg.RefreshToken != "" && (cfg.NeedsRefresh() || !cfg.IsTokenValid()) {
+		if err := RefreshToken(cfg); err != nil {
+			return nil, err
 		}
 	}
 
@@ -41,72 +54,603 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, fmt.Errorf("invalid or expired token")
 	}
 
+	httpClient, err := newHTTPClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	dbxConfig := dropbox.Config{
-		Token: cfg.AccessToken,
+		Token:      cfg.Token(),
+		Client:     httpClient,
+		AsMemberID: cfg.TeamMemberID,
+	}
+
+	if err := applyPathRoot(&dbxConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := config.ValidateBasePath(cfg.BasePath); err != nil {
+		return nil, err
 	}
 
 	return &Client{
-		filesClient:   files.New(dbxConfig),
-		sharingClient: sharing.New(dbxConfig),
+		filesClient:      files.New(dbxConfig),
+		sharingClient:    sharing.New(dbxConfig),
+		teamClient:       team.New(dbxConfig),
+		usersClient:      users.New(dbxConfig),
+		propertiesClient: file_properties.New(dbxConfig),
+		paperClient:      paper.New(dbxConfig),
+		config:           cfg,
+		basePath:         strings.TrimSuffix(cfg.BasePath, "/"),
+	}, nil
+}
+
+// NewClientFromSDK creates a client that makes its requests through the given
+// SDK clients. It lets tests outside this package run against fakes; clients
+// for other APIs are left unset.
+func NewClientFromSDK(cfg *config.Config, filesClient files.Client, sharingClient sharing.Client) *Client {
+	return &Client{
+		filesClient:   filesClient,
+		sharingClient: sharingClient,
 		config:        cfg,
+		basePath:      strings.TrimSuffix(cfg.BasePath, "/"),
+	}
+}
+
+// applyPathRoot sets the Dropbox-API-Path-Root header on dbxConfig according
+// to cfg.PathRoot. The team root namespace is looked up once and cached.
+func applyPathRoot(dbxConfig *dropbox.Config, cfg *config.Config) error {
+	switch cfg.PathRoot {
+	case "":
+		return nil
+	case config.PathRootHome:
+		dbxConfig.PathRoot = `{".tag": "home"}`
+		return nil
+	case config.PathRootRoot:
+		if cfg.RootNamespaceID() == "" {
+			account, err := users.New(*dbxConfig).GetCurrentAccount()
+			if err != nil {
+				return fmt.Errorf("failed to look up root namespace: %w", err)
+			}
+			switch info := account.RootInfo.(type) {
+			case *common.TeamRootInfo:
+				cfg.SetRootNamespaceID(info.RootNamespaceId)
+			case *common.UserRootInfo:
+				cfg.SetRootNamespaceID(info.RootNamespaceId)
+			default:
+				return fmt.Errorf("failed to look up root namespace: unknown root info")
+			}
+		}
+		*dbxConfig = dbxConfig.WithRoot(cfg.RootNamespaceID())
+		return nil
+	}
+
+	if err := config.ValidatePathRoot(cfg.PathRoot); err != nil {
+		return err
+	}
+	*dbxConfig = dbxConfig.WithNamespaceID(cfg.PathRoot)
+	return nil
+}
+
+// refreshMu serializes token refreshes, which update the shared config.
+var refreshMu sync.Mutex
+
+// RefreshToken exchanges the configured refresh token for a new access token
+// and saves it to the config file.
+func RefreshToken(cfg *config.Config) error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return refreshToken(cfg)
+}
+
+// refreshToken exchanges the refresh token for a new access token and saves
+// it to the config file.
+func refreshToken(cfg *config.Config) error {
+	transport, err := transportFor(cfg)
+	if err != nil {
+		return err
+	}
+
+	authConfig := auth.OAuthConfig{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		HTTPClient:   &http.Client{Transport: transport},
+	}
+	result, err := auth.RefreshToken(authConfig, cfg.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+	cfg.UpdateTokens(result.AccessToken, result.RefreshToken, result.ExpiresAt)
+	cfg.UpdateScopes(result.Scopes)
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save updated config: %w", err)
+	}
+	return nil
+}
+
+// validateTimeout bounds the request that checks a token.
+const validateTimeout = 10 * time.Second
+
+// ValidateToken checks the access token of cfg with Dropbox through the
+// configured proxy, transport and User-Agent. Unlike other requests, an
+// expired or revoked token is reported rather than refreshed.
+func ValidateToken(cfg *config.Config) error {
+	transport, err := transportFor(cfg)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &userAgentTransport{userAgent: userAgentFor(cfg), base: transport},
+		Timeout:   validateTimeout,
+	}
+	return auth.ValidateToken(client, cfg.Token())
+}
+
+// DefaultUserAgent identifies the server to Dropbox unless the configuration
+// sets another User-Agent. main appends the build version.
+var DefaultUserAgent = "dropbox-mcp-server"
+
+// newHTTPClient returns an authorized HTTP client whose requests all carry ctx.
+// The SDK does not accept a context per call, so it is attached here instead.
+func newHTTPClient(ctx context.Context, cfg *config.Config) (*http.Client, error) {
+	transport, err := transportFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &authTransport{
+			cfg: cfg,
+			base: &userAgentTransport{
+				userAgent: userAgentFor(cfg),
+				base:      &contextTransport{ctx: ctx, base: &failureTransport{base: transport}},
+			},
+		},
+		Timeout: EnvDuration("DROPBOX_MCP_REQUEST_TIMEOUT", 0),
 	}, nil
 }
 
+// userAgentFor returns the User-Agent configured for cfg, or
+// DefaultUserAgent.
+func userAgentFor(cfg *config.Config) string {
+	if cfg.UserAgent != "" {
+		return cfg.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// GetCurrentAccount returns the account the token belongs to, or the selected
+// team member's account.
+func (c *Client) GetCurrentAccount() (*users.FullAccount, error) {
+	account, err := c.usersClient.GetCurrentAccount()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current account: %w", err)
+	}
+	return account, nil
+}
+
+// GetSpaceUsage returns the space used by the account and its allocation.
+func (c *Client) GetSpaceUsage() (*users.SpaceUsage, error) {
+	usage, err := c.usersClient.GetSpaceUsage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get space usage: %w", err)
+	}
+	return usage, nil
+}
+
+// CheckPathRoot verifies that the configured path root is accessible. Dropbox
+// rejects an inaccessible namespace with a no_permission path root error.
+func (c *Client) CheckPathRoot() error {
+	arg := files.NewListFolderArg("")
+	arg.Limit = 1
+
+	if _, err := c.filesClient.ListFolder(arg); err != nil {
+		var sdkErr dropbox.SDKInternalError
+		if errors.As(err, &sdkErr) && strings.Contains(sdkErr.Content, "no_permission") {
+			return fmt.Errorf("no permission to access namespace %s", c.config.PathRoot)
+		}
+		if errors.As(err, &sdkErr) && strings.Contains(sdkErr.Content, "invalid_root") {
+			return fmt.Errorf("root namespace has changed, set the path root again")
+		}
+		return fmt.Errorf("failed to access path root: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) ListFolder(path string) ([]files.IsMetadata, error) {
-	if path == "" {
-		path = ""
+	return c.listFolder(path, false)
+}
+
+// ListFolderRecursive lists path and all of its descendants.
+func (c *Client) ListFolderRecursive(path string) ([]files.IsMetadata, error) {
+	return c.listFolder(path, true)
+}
+
+func (c *Client) listFolder(path string, recursive bool) ([]files.IsMetadata, error) {
+	entries, _, err := c.ListFolderWithCursor(path, recursive)
+	return entries, err
+}
+
+// ListFolderWithCursor lists path, and its descendants when recursive is set,
+// and returns a cursor that ListFolderChanges accepts to fetch later changes.
+func (c *Client) ListFolderWithCursor(path string, recursive bool) ([]files.IsMetadata, string, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, "", err
 	}
 
 	arg := files.NewListFolderArg(path)
-	arg.Recursive = false
+	arg.Recursive = recursive
 	arg.IncludeDeleted = false
 
+	res, err := c.filesClient.ListFolder(arg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	return c.continueListing(res)
+}
+
+// ListFolderPage lists a single page of at most limit entries of path. The
+// returned cursor is passed to ListFolderContinuePage for the next page and
+// is empty once the listing is complete.
+func (c *Client) ListFolderPage(path string, recursive bool, limit uint32) ([]files.IsMetadata, string, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	arg := files.NewListFolderArg(path)
+	arg.Recursive = recursive
+	arg.IncludeDeleted = false
+	arg.Limit = limit
+
+	res, err := c.filesClient.ListFolder(arg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	return c.listPage(res)
+}
+
+// ListFolderContinuePage returns the page of a listing that follows cursor.
+func (c *Client) ListFolderContinuePage(cursor string) ([]files.IsMetadata, string, error) {
+	res, err := c.filesClient.ListFolderContinue(files.NewListFolderContinueArg(cursor))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to continue listing: %w", err)
+	}
+
+	return c.listPage(res)
+}
+
+func (c *Client) listPage(res *files.ListFolderResult) ([]files.IsMetadata, string, error) {
+	entries := c.unscopeEntries(res.Entries)
+
+	if !res.HasMore {
+		return entries, "", nil
+	}
+	return entries, res.Cursor, nil
+}
+
+// ListDeleted returns the deleted files and folders directly in path, or
+// anywhere below it when recursive is set.
+func (c *Client) ListDeleted(path string, recursive bool) ([]*files.DeletedMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := files.NewListFolderArg(path)
+	arg.Recursive = recursive
+	arg.IncludeDeleted = true
+
 	res, err := c.filesClient.ListFolder(arg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list folder: %w", err)
 	}
 
+	entries, _, err := c.continueListing(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []*files.DeletedMetadata
+	for _, entry := range entries {
+		if d, ok := entry.(*files.DeletedMetadata); ok {
+			deleted = append(deleted, d)
+		}
+	}
+
+	return deleted, nil
+}
+
+// ListFolderChanges returns the entries that changed since cursor was issued,
+// with DeletedMetadata for removed items, and a cursor for the next call.
+func (c *Client) ListFolderChanges(cursor string) ([]files.IsMetadata, string, error) {
+	res, err := c.filesClient.ListFolderContinue(files.NewListFolderContinueArg(cursor))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	return c.continueListing(res)
+}
+
+// continueListing fetches the remaining pages of a listing.
+func (c *Client) continueListing(res *files.ListFolderResult) ([]files.IsMetadata, string, error) {
+	var err error
+
 	entries := res.Entries
 	for res.HasMore {
 		arg := files.NewListFolderContinueArg(res.Cursor)
 		res, err = c.filesClient.ListFolderContinue(arg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to continue listing: %w", err)
+			return nil, "", fmt.Errorf("failed to continue listing: %w", err)
 		}
 		entries = append(entries, res.Entries...)
 	}
 
-	return entries, nil
+	return c.unscopeEntries(entries), res.Cursor, nil
 }
 
-func (c *Client) Search(query, path string) ([]*files.SearchMatchV2, error) {
+// GetLatestCursor returns a cursor for the current state of path without
+// listing it, so that later changes can be fetched with ListFolderChanges.
+func (c *Client) GetLatestCursor(path string, recursive, includeDeleted bool) (string, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	arg := files.NewListFolderArg(path)
+	arg.Recursive = recursive
+	arg.IncludeDeleted = includeDeleted
+
+	res, err := c.filesClient.ListFolderGetLatestCursor(arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest cursor: %w", err)
+	}
+
+	return res.Cursor, nil
+}
+
+// Longpoll waits up to timeout seconds for changes to the listing of cursor,
+// plus up to 90 seconds of jitter that Dropbox adds. It reports whether
+// anything changed and how many seconds to wait before polling again.
+func (c *Client) Longpoll(cursor string, timeout uint64) (bool, uint64, error) {
+	arg := files.NewListFolderLongpollArg(cursor)
+	arg.Timeout = timeout
+
+	res, err := c.filesClient.ListFolderLongpoll(arg)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to wait for changes: %w", err)
+	}
+	return res.Changes, res.Backoff, nil
+}
+
+// IsCursorReset reports whether err is a listing or longpoll failure because
+// the cursor has expired and the folder must be listed again from scratch.
+func IsCursorReset(err error) bool {
+	var continueErr files.ListFolderContinueAPIError
+	var longpollErr files.ListFolderLongpollAPIError
+	switch {
+	case errors.As(err, &continueErr):
+		return continueErr.EndpointError != nil && continueErr.EndpointError.Tag == files.ListFolderContinueErrorReset
+	case errors.As(err, &longpollErr):
+		return longpollErr.EndpointError != nil && longpollErr.EndpointError.Tag == files.ListFolderLongpollErrorReset
+	}
+	return false
+}
+
+// Search returns the first page of matches for query, below path when it is
+// set. Matches are ordered by orderBy, files.SearchOrderByRelevance or
+// files.SearchOrderByLastModifiedTime, or by relevance when it is empty. The
+// returned cursor fetches the next page with SearchContinue and is empty when
+// there are no more matches.
+func (c *Client) Search(query, path, orderBy string) ([]*files.SearchMatchV2, string, error) {
 	options := files.NewSearchOptions()
-	if path != "" {
-		options.Path = path
+	if path != "" || c.basePath != "" {
+		scoped, err := c.scopePath(path)
+		if err != nil {
+			return nil, "", err
+		}
+		options.Path = scoped
 	}
 	options.MaxResults = 100
+	if orderBy != "" {
+		options.OrderBy = &files.SearchOrderBy{Tagged: dropbox.Tagged{Tag: orderBy}}
+	}
 
 	arg := files.NewSearchV2Arg(query)
 	arg.Options = options
 
 	res, err := c.filesClient.SearchV2(arg)
 	if err != nil {
-		return nil, fmt.Errorf("search failed: %w", err)
+		return nil, "", fmt.Errorf("search failed: %w", err)
+	}
+
+	return c.searchResult(res)
+}
+
+// SearchContinue returns the next page of matches of a search.
+func (c *Client) SearchContinue(cursor string) ([]*files.SearchMatchV2, string, error) {
+	res, err := c.filesClient.SearchContinueV2(files.NewSearchV2ContinueArg(cursor))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to continue search: %w", err)
 	}
 
-	matches := res.Matches
-	// Note: Search pagination is not currently supported in the SDK version we're using
-	// Only return first page of results
+	return c.searchResult(res)
+}
 
-	return matches, nil
+func (c *Client) searchResult(res *files.SearchV2Result) ([]*files.SearchMatchV2, string, error) {
+	// A search continued from a caller's cursor can cover the whole Dropbox
+	matches := res.Matches[:0]
+	for _, match := range res.Matches {
+		if match.Metadata != nil {
+			if !c.inBasePath(metadataPathLower(match.Metadata.Metadata)) {
+				continue
+			}
+			c.unscopeMetadata(match.Metadata.Metadata)
+		}
+		matches = append(matches, match)
+	}
+
+	if !res.HasMore {
+		return matches, "", nil
+	}
+	return matches, res.Cursor, nil
 }
 
 func (c *Client) GetMetadata(path string) (files.IsMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	arg := files.NewGetMetadataArg(path)
-	return c.filesClient.GetMetadata(arg)
+	metadata, err := c.filesClient.GetMetadata(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.unscopeMetadata(metadata)
+	return metadata, nil
+}
+
+// GetMetadataVerbose is like GetMetadata but also requests media info,
+// explicit shared member flags and the property groups of every template the
+// user has added.
+func (c *Client) GetMetadataVerbose(path string) (files.IsMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := files.NewGetMetadataArg(path)
+	arg.IncludeMediaInfo = true
+	arg.IncludeHasExplicitSharedMembers = true
+
+	// Property groups must be requested by template, and an account without
+	// templates has none to return
+	if templates, err := c.propertiesClient.TemplatesListForUser(); err == nil && len(templates.TemplateIds) > 0 {
+		arg.IncludePropertyGroups = &file_properties.TemplateFilterBase{
+			Tagged:     dropbox.Tagged{Tag: file_properties.TemplateFilterBaseFilterSome},
+			FilterSome: templates.TemplateIds,
+		}
+	}
+
+	metadata, err := c.filesClient.GetMetadata(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.unscopeMetadata(metadata)
+	return metadata, nil
+}
+
+// IsNotFound reports whether err is a metadata or folder listing failure
+// because the path does not exist.
+func IsNotFound(err error) bool {
+	var lookupErr *files.LookupError
+
+	var metadataErr files.GetMetadataAPIError
+	var listErr files.ListFolderAPIError
+	switch {
+	case errors.As(err, &metadataErr) && metadataErr.EndpointError != nil:
+		lookupErr = metadataErr.EndpointError.Path
+	case errors.As(err, &listErr) && listErr.EndpointError != nil:
+		lookupErr = listErr.EndpointError.Path
+	}
+
+	return lookupErr != nil && lookupErr.Tag == files.LookupErrorNotFound
+}
+
+// IsFolderConflict reports whether err is a folder creation failure because a
+// folder already exists at the path. Conflicts with files are not included.
+func IsFolderConflict(err error) bool {
+	var createErr files.CreateFolderV2APIError
+	if !errors.As(err, &createErr) || createErr.EndpointError == nil {
+		return false
+	}
+
+	writeErr := createErr.EndpointError.Path
+	return writeErr != nil && writeErr.Tag == files.WriteErrorConflict &&
+		writeErr.Conflict != nil && writeErr.Conflict.Tag == files.WriteConflictErrorFolder
+}
+
+// IsDestinationConflict reports whether err is a move failure because
+// something already exists at the destination path.
+func IsDestinationConflict(err error) bool {
+	var moveErr files.MoveV2APIError
+	if !errors.As(err, &moveErr) || moveErr.EndpointError == nil {
+		return false
+	}
+
+	relocationErr := moveErr.EndpointError
+	return relocationErr.Tag == files.RelocationErrorTo && relocationErr.To != nil &&
+		relocationErr.To.Tag == files.WriteErrorConflict
+}
+
+// IsDestinationFileConflict reports whether err is a move failure because a
+// file already exists at the destination path. It is false when the
+// destination is a folder.
+func IsDestinationFileConflict(err error) bool {
+	var moveErr files.MoveV2APIError
+	if !IsDestinationConflict(err) || !errors.As(err, &moveErr) {
+		return false
+	}
+
+	conflict := moveErr.EndpointError.To.Conflict
+	return conflict != nil && conflict.Tag == files.WriteConflictErrorFile
+}
+
+// IsUploadConflict reports whether err is an upload failure because a file
+// or folder already exists at the path.
+func IsUploadConflict(err error) bool {
+	var writeErr *files.WriteError
+
+	var uploadErr files.UploadAPIError
+	var finishErr files.UploadSessionFinishAPIError
+	switch {
+	case errors.As(err, &uploadErr) && uploadErr.EndpointError != nil && uploadErr.EndpointError.Path != nil:
+		writeErr = uploadErr.EndpointError.Path.Reason
+	case errors.As(err, &finishErr) && finishErr.EndpointError != nil:
+		writeErr = finishErr.EndpointError.Path
+	}
+
+	return writeErr != nil && writeErr.Tag == files.WriteErrorConflict
+}
+
+// MoveErrorTag returns the reason a move failed, such as
+// files.RelocationErrorCantTransferOwnership, or an empty string if err is
+// not a move failure.
+func MoveErrorTag(err error) string {
+	var moveErr files.MoveV2APIError
+	if !errors.As(err, &moveErr) || moveErr.EndpointError == nil {
+		return ""
+	}
+	return moveErr.EndpointError.Tag
 }
 
 func (c *Client) Download(path string) ([]byte, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.download(path)
+}
+
+// download downloads path as given, without applying the base path.
+func (c *Client) download(path string) ([]byte, error) {
 	arg := files.NewDownloadArg(path)
 	_, content, err := c.filesClient.Download(arg)
 	if err != nil {
@@ -122,41 +666,134 @@ func (c *Client) Download(path string) ([]byte, error) {
 	return data, nil
 }
 
-func (c *Client) Upload(path, content, mode string) (*files.FileMetadata, error) {
-	var data []byte
+// DownloadRevision downloads a specific revision of the file at path. A
+// revision is looked up by its ID alone, so with a base path set the file it
+// belongs to is checked to be inside the base path before its content is read.
+func (c *Client) DownloadRevision(path, rev string) ([]byte, error) {
+	if _, err := c.scopePath(path); err != nil {
+		return nil, err
+	}
 
-	if strings.Contains(content, "\n") || !isBase64(content) {
-		data = []byte(content)
-	} else {
-		decoded, err := base64.StdEncoding.DecodeString(content)
-		if err != nil {
-			data = []byte(content)
-		} else {
-			data = decoded
-		}
+	metadata, content, err := c.filesClient.Download(files.NewDownloadArg("rev:" + rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download revision %s of %s: %w", rev, path, err)
+	}
+	defer content.Close()
+
+	if c.basePath != "" && (metadata == nil || !c.inBasePath(metadata.PathLower)) {
+		return nil, fmt.Errorf("revision %s is not a revision of a file within the base path", rev)
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	return data, nil
+}
+
+// UploadOptions controls how an upload is committed.
+type UploadOptions struct {
+	// Mode is "add", "overwrite" or "update". Defaults to "add".
+	Mode string
+	// Rev is the revision the upload is expected to replace in "update" mode.
+	Rev string
+	// ClientModified is the modification time recorded for the file. Dropbox
+	// stores it with second precision. Defaults to the current time.
+	ClientModified *time.Time
+	// FailOnConflict fails an upload that conflicts with an existing file
+	// instead of saving it under a renamed path.
+	FailOnConflict bool
+}
+
+// commitInfo builds the CommitInfo shared by single-shot and chunked uploads.
+func (o UploadOptions) commitInfo(path string) (*files.CommitInfo, error) {
+	mode, err := o.writeMode()
+	if err != nil {
+		return nil, err
 	}
 
 	commitInfo := files.NewCommitInfo(path)
-	if mode == "overwrite" {
-		commitInfo.Mode = &files.WriteMode{Tagged: dropbox.Tagged{Tag: "overwrite"}}
-	} else {
-		commitInfo.Mode = &files.WriteMode{Tagged: dropbox.Tagged{Tag: "add"}}
+	commitInfo.Mode = mode
+	// Unless asked to fail, a conflicting upload is saved under a renamed
+	// path, which the caller can detect from the result.
+	commitInfo.Autorename = !o.FailOnConflict
+
+	clientModified := time.Now()
+	if o.ClientModified != nil {
+		clientModified = *o.ClientModified
 	}
-	commitInfo.Autorename = true
-	now := time.Now().UTC()
-	commitInfo.ClientModified = &now
+	clientModified = clientModified.UTC().Truncate(time.Second)
+	commitInfo.ClientModified = &clientModified
+
+	return commitInfo, nil
+}
+
+func (o UploadOptions) writeMode() (*files.WriteMode, error) {
+	switch o.Mode {
+	case "", "add":
+		return &files.WriteMode{Tagged: dropbox.Tagged{Tag: files.WriteModeAdd}}, nil
+	case "overwrite":
+		return &files.WriteMode{Tagged: dropbox.Tagged{Tag: files.WriteModeOverwrite}}, nil
+	case "update":
+		if o.Rev == "" {
+			return nil, fmt.Errorf("rev is required for update mode")
+		}
+		return &files.WriteMode{Tagged: dropbox.Tagged{Tag: files.WriteModeUpdate}, Update: o.Rev}, nil
+	}
+	return nil, fmt.Errorf("unsupported upload mode: %s", o.Mode)
+}
 
-	reader := bytes.NewReader(data)
+// DecodeContent returns the bytes of an upload's content argument, which is
+// either text or base64 encoded.
+func DecodeContent(content string) []byte {
+	if strings.Contains(content, "\n") || !isBase64(content) {
+		return []byte(content)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return []byte(content)
+	}
+	return decoded
+}
 
-	if len(data) > 150*1024*1024 {
-		return c.uploadLarge(commitInfo, reader)
+// Upload uploads data to path.
+func (c *Client) Upload(path string, data []byte, opts UploadOptions) (*files.FileMetadata, error) {
+	return c.UploadStream(path, bytes.NewReader(data), int64(len(data)), opts)
+}
+
+// largeUploadThreshold is the size above which uploads use an upload session.
+const largeUploadThreshold = 150 * 1024 * 1024
+
+// UploadStream uploads size bytes read from r to path, switching to a chunked
+// upload session for large files. A negative size means the size is unknown,
+// as for content streamed from the network, which is uploaded in chunks too.
+func (c *Client) UploadStream(path string, r io.Reader, size int64, opts UploadOptions) (*files.FileMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	commitInfo, err := opts.commitInfo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both paths commit with the same CommitInfo so that mode, autorename
+	// and client_modified apply regardless of size.
+	var metadata *files.FileMetadata
+	if size < 0 || size > largeUploadThreshold {
+		metadata, err = c.uploadLarge(commitInfo, r)
+	} else {
+		arg := &files.UploadArg{CommitInfo: *commitInfo}
+		metadata, err = c.filesClient.Upload(arg, r)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	arg := files.NewUploadArg(path)
-	arg.Mode = commitInfo.Mode
-	arg.Autorename = commitInfo.Autorename
-	arg.ClientModified = commitInfo.ClientModified
-	return c.filesClient.Upload(arg, reader)
+	c.unscopeMetadata(metadata)
+	return metadata, nil
 }
 
 func (c *Client) uploadLarge(commitInfo *files.CommitInfo, reader io.Reader) (*files.FileMetadata, error) {
@@ -173,7 +810,9 @@ func (c *Client) uploadLarge(commitInfo *files.CommitInfo, reader io.Reader) (*f
 	buffer := make([]byte, chunkSize)
 
 	for {
-		n, err := reader.Read(buffer)
+		// Network readers return short reads, so chunks are filled up
+		// rather than appended as they arrive
+		n, err := io.ReadFull(reader, buffer)
 		if n > 0 {
 			cursor := files.NewUploadSessionCursor(session.SessionId, offset)
 			appendArg := files.NewUploadSessionAppendArg(cursor)
@@ -184,7 +823,7 @@ func (c *Client) uploadLarge(commitInfo *files.CommitInfo, reader io.Reader) (*f
 			offset += uint64(n) // #nosec G115 - n is bounded by chunkSize
 		}
 
-		if err == io.EOF {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			break
 		}
 		if err != nil {
@@ -199,6 +838,11 @@ func (c *Client) uploadLarge(commitInfo *files.CommitInfo, reader io.Reader) (*f
 }
 
 func (c *Client) CreateFolder(path string) (*files.FolderMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	arg := files.NewCreateFolderArg(path)
 	arg.Autorename = false
 
@@ -208,23 +852,151 @@ func (c *Client) CreateFolder(path string) (*files.FolderMetadata, error) {
 	}
 
 	// result.Metadata is already a *files.FolderMetadata
+	c.unscopeMetadata(result.Metadata)
 	return result.Metadata, nil
 }
 
-func (c *Client) Move(fromPath, toPath string) (files.IsMetadata, error) {
+// CreateFolderRecursive creates the folder at path and any missing ancestors,
+// like mkdir -p. Folders that already exist are left alone. It reports whether
+// the deepest folder was newly created.
+func (c *Client) CreateFolderRecursive(path string) (*files.FolderMetadata, bool, error) {
+	var current string
+	var folder *files.FolderMetadata
+	var created bool
+
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		current += "/" + segment
+
+		metadata, err := c.CreateFolder(current)
+		switch {
+		case err == nil:
+			folder, created = metadata, true
+		case IsFolderConflict(err):
+			folder, created = nil, false
+		default:
+			return nil, false, err
+		}
+	}
+
+	if current == "" {
+		return nil, false, fmt.Errorf("cannot create the root folder")
+	}
+
+	if folder == nil {
+		metadata, err := c.GetMetadata(current)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get existing folder: %w", err)
+		}
+		existing, ok := metadata.(*files.FolderMetadata)
+		if !ok {
+			return nil, false, fmt.Errorf("%s is not a folder", current)
+		}
+		folder = existing
+	}
+
+	return folder, created, nil
+}
+
+// Move moves fromPath to toPath. Moves between namespaces, such as from a
+// member folder into a team folder, can change who owns the content and fail
+// unless allowOwnershipTransfer is set.
+func (c *Client) Move(fromPath, toPath string, allowOwnershipTransfer bool) (files.IsMetadata, error) {
+	fromPath, err := c.scopePath(fromPath)
+	if err != nil {
+		return nil, err
+	}
+	toPath, err = c.scopePath(toPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dropbox paths are case-insensitive, so a move that only changes case
+	// conflicts with the source and has to go through a temporary name
+	if fromPath != toPath && strings.EqualFold(fromPath, toPath) {
+		return c.moveCaseOnly(fromPath, toPath, allowOwnershipTransfer)
+	}
+
+	metadata, err := c.move(fromPath, toPath, allowOwnershipTransfer)
+	if err != nil {
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+
+	c.unscopeMetadata(metadata)
+	return metadata, nil
+}
+
+// caseRenameAttempts bounds how many temporary names a case-only rename
+// tries before giving up.
+const caseRenameAttempts = 3
+
+// moveCaseOnly renames fromPath to toPath, which differ only in case, by
+// moving it to a temporary sibling name first. If the second move fails the
+// item is moved back so it is not left under the temporary name.
+func (c *Client) moveCaseOnly(fromPath, toPath string, allowOwnershipTransfer bool) (files.IsMetadata, error) {
+	var tempPath string
+	var err error
+	for attempt := 0; attempt < caseRenameAttempts; attempt++ {
+		tempPath, err = caseRenameTempPath(fromPath)
+		if err != nil {
+			return nil, err
+		}
+		_, err = c.move(fromPath, tempPath, allowOwnershipTransfer)
+		// A random name that happens to exist is retried with another one
+		if err == nil || !IsDestinationConflict(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+
+	metadata, err := c.move(tempPath, toPath, allowOwnershipTransfer)
+	if err != nil {
+		if _, restoreErr := c.move(tempPath, fromPath, allowOwnershipTransfer); restoreErr != nil {
+			return nil, fmt.Errorf("move failed: %w; the item was left at %s: %v", err, c.unscopePath(tempPath), restoreErr)
+		}
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+
+	c.unscopeMetadata(metadata)
+	return metadata, nil
+}
+
+// caseRenameTempPath returns a randomly named sibling of p to hold an item
+// during a case-only rename.
+func caseRenameTempPath(p string) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate a temporary name: %w", err)
+	}
+	return fmt.Sprintf("%s.case-rename-%x", p, suffix), nil
+}
+
+func (c *Client) move(fromPath, toPath string, allowOwnershipTransfer bool) (files.IsMetadata, error) {
 	arg := files.NewRelocationArg(fromPath, toPath)
 	arg.Autorename = false
-	arg.AllowOwnershipTransfer = false
+	arg.AllowOwnershipTransfer = allowOwnershipTransfer
 
 	result, err := c.filesClient.MoveV2(arg)
 	if err != nil {
-		return nil, fmt.Errorf("move failed: %w", err)
+		return nil, err
 	}
-
 	return result.Metadata, nil
 }
 
 func (c *Client) Copy(fromPath, toPath string) (files.IsMetadata, error) {
+	fromPath, err := c.scopePath(fromPath)
+	if err != nil {
+		return nil, err
+	}
+	toPath, err = c.scopePath(toPath)
+	if err != nil {
+		return nil, err
+	}
+
 	arg := files.NewRelocationArg(fromPath, toPath)
 	arg.Autorename = false
 
@@ -233,13 +1005,19 @@ func (c *Client) Copy(fromPath, toPath string) (files.IsMetadata, error) {
 		return nil, fmt.Errorf("copy failed: %w", err)
 	}
 
+	c.unscopeMetadata(result.Metadata)
 	return result.Metadata, nil
 }
 
 func (c *Client) Delete(path string) error {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return err
+	}
+
 	arg := files.NewDeleteArg(path)
 
-	_, err := c.filesClient.DeleteV2(arg)
+	_, err = c.filesClient.DeleteV2(arg)
 	if err != nil {
 		return fmt.Errorf("delete failed: %w", err)
 	}
@@ -248,7 +1026,12 @@ func (c *Client) Delete(path string) error {
 }
 
 func (c *Client) CreateSharedLink(path string, settings map[string]interface{}) (string, error) {
-	arg := sharing.NewCreateSharedLinkWithSettingsArg(path)
+	scoped, err := c.scopePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	arg := sharing.NewCreateSharedLinkWithSettingsArg(scoped)
 
 	if settings != nil {
 		linkSettings := &sharing.SharedLinkSettings{}
@@ -270,7 +1053,7 @@ func (c *Client) CreateSharedLink(path string, settings map[string]interface{})
 	result, err := c.sharingClient.CreateSharedLinkWithSettings(arg)
 	if err != nil {
 		if strings.Contains(err.Error(), "shared_link_already_exists") {
-			links, listErr := c.ListSharedLinks(path)
+			links, listErr := c.ListSharedLinks(path, true)
 			if listErr == nil && len(links) > 0 {
 				switch l := links[0].(type) {
 				case *sharing.FileLinkMetadata:
@@ -292,19 +1075,65 @@ func (c *Client) CreateSharedLink(path string, settings map[string]interface{})
 	return "", fmt.Errorf("unexpected shared link type")
 }
 
-func (c *Client) ListSharedLinks(path string) ([]sharing.IsSharedLinkMetadata, error) {
+// ListSharedLinks lists shared links for path, or for the whole account when
+// path is empty. Unless directOnly is set, links inherited from a shared parent
+// folder are included as well.
+func (c *Client) ListSharedLinks(path string, directOnly bool) ([]sharing.IsSharedLinkMetadata, error) {
+	if path != "" {
+		scoped, err := c.scopePath(path)
+		if err != nil {
+			return nil, err
+		}
+		path = scoped
+	}
+
 	arg := sharing.NewListSharedLinksArg()
 	arg.Path = path
+	arg.DirectOnly = directOnly
 
 	result, err := c.sharingClient.ListSharedLinks(arg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list shared links: %w", err)
 	}
 
-	return result.Links, nil
+	links := result.Links
+	for result.HasMore {
+		arg := sharing.NewListSharedLinksArg()
+		arg.Path = path
+		arg.DirectOnly = directOnly
+		arg.Cursor = result.Cursor
+		result, err = c.sharingClient.ListSharedLinks(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to continue listing shared links: %w", err)
+		}
+		links = append(links, result.Links...)
+	}
+
+	// Without a path the whole account is listed, so drop links to items
+	// outside the base path
+	scopedLinks := links[:0]
+	for _, link := range links {
+		if !c.inBasePath(linkPathLower(link)) {
+			continue
+		}
+		c.unscopeLink(link)
+		scopedLinks = append(scopedLinks, link)
+	}
+
+	return scopedLinks, nil
 }
 
 func (c *Client) RevokeSharedLink(url string) error {
+	if c.basePath != "" {
+		link, err := c.sharingClient.GetSharedLinkMetadata(sharing.NewGetSharedLinkMetadataArg(url))
+		if err != nil {
+			return fmt.Errorf("failed to look up shared link: %w", err)
+		}
+		if !c.inBasePath(linkPathLower(link)) {
+			return fmt.Errorf("shared link is outside the base path")
+		}
+	}
+
 	arg := sharing.NewRevokeSharedLinkArg(url)
 
 	err := c.sharingClient.RevokeSharedLink(arg)
@@ -315,22 +1144,187 @@ func (c *Client) RevokeSharedLink(url string) error {
 	return nil
 }
 
-func (c *Client) GetRevisions(path string) ([]*files.FileMetadata, error) {
+// DownloadSharedLink downloads the file a shared link points to, which may be
+// in someone else's Dropbox. For folder links, path selects a file relative
+// to the shared folder. Files larger than maxBytes are rejected before their
+// content is read.
+func (c *Client) DownloadSharedLink(url, path, password string, maxBytes int64) (sharing.IsSharedLinkMetadata, []byte, error) {
+	arg := sharing.NewGetSharedLinkMetadataArg(url)
+	arg.Path = path
+	arg.LinkPassword = password
+
+	link, content, err := c.sharingClient.GetSharedLinkFile(arg)
+	if err != nil {
+		return nil, nil, sharedLinkFileError(err)
+	}
+	defer content.Close()
+
+	// Links to the user's own files are subject to the base path
+	if pathLower := linkPathLower(link); pathLower != "" && !c.inBasePath(pathLower) {
+		return nil, nil, fmt.Errorf("shared link is outside the base path")
+	}
+
+	if file, ok := link.(*sharing.FileLinkMetadata); ok && int64(file.Size) > maxBytes {
+		return nil, nil, fmt.Errorf("%s is %d bytes, which exceeds the %d byte download limit", file.Name, file.Size, maxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxBytes+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, nil, fmt.Errorf("shared file exceeds the %d byte download limit", maxBytes)
+	}
+
+	c.unscopeLink(link)
+	return link, data, nil
+}
+
+// sharedLinkFileError explains the common reasons a shared link cannot be
+// downloaded.
+func sharedLinkFileError(err error) error {
+	var linkErr sharing.GetSharedLinkFileAPIError
+	if errors.As(err, &linkErr) && linkErr.EndpointError != nil {
+		switch linkErr.EndpointError.Tag {
+		case sharing.GetSharedLinkFileErrorSharedLinkNotFound:
+			return fmt.Errorf("shared link not found, it may have expired or been revoked")
+		case sharing.GetSharedLinkFileErrorSharedLinkAccessDenied:
+			return fmt.Errorf("access to the shared link was denied, it may require a link_password or be restricted to certain users")
+		case sharing.GetSharedLinkFileErrorSharedLinkIsDirectory:
+			return fmt.Errorf("shared link points to a folder, pass path to select a file within it")
+		case sharing.GetSharedLinkFileErrorUnsupportedLinkType:
+			return fmt.Errorf("this type of shared link cannot be downloaded")
+		}
+	}
+	return fmt.Errorf("failed to download shared link: %w", err)
+}
+
+// GetRevisions lists up to limit revisions of a file, newest first, and
+// whether the file is currently deleted. With mode "id" the history follows
+// the file across moves and renames; with "path" or "" it covers whatever was
+// stored at path.
+func (c *Client) GetRevisions(path, mode string, limit uint64) (*files.ListRevisionsResult, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	arg := files.NewListRevisionsArg(path)
-	arg.Limit = 100
+	arg.Limit = limit
+	if mode != "" {
+		arg.Mode = &files.ListRevisionsMode{Tagged: dropbox.Tagged{Tag: mode}}
+	}
 
 	result, err := c.filesClient.ListRevisions(arg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get revisions: %w", err)
 	}
 
-	return result.Entries, nil
+	for _, entry := range result.Entries {
+		c.unscopeMetadata(entry)
+	}
+
+	return result, nil
 }
 
 func (c *Client) RestoreFile(path, rev string) (*files.FileMetadata, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	arg := files.NewRestoreArg(path, rev)
 
-	return c.filesClient.Restore(arg)
+	metadata, err := c.filesClient.Restore(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.unscopeMetadata(metadata)
+	return metadata, nil
+}
+
+func (c *Client) GetTemporaryLink(path string) (string, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	arg := files.NewGetTemporaryLinkArg(path)
+
+	result, err := c.filesClient.GetTemporaryLink(arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get temporary link: %w", err)
+	}
+
+	return result.Link, nil
+}
+
+// ErrNonExportable is returned by Export when the file has no export format.
+var ErrNonExportable = errors.New("file is not exportable")
+
+// Export exports a file that cannot be downloaded directly, such as a Paper
+// doc, to format. An empty format uses the file's default export format.
+func (c *Client) Export(path, format string) (*files.ExportResult, []byte, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	arg := files.NewExportArg(path)
+	arg.ExportFormat = format
+
+	res, content, err := c.filesClient.Export(arg)
+	if err != nil {
+		var apiErr files.ExportAPIError
+		if errors.As(err, &apiErr) && apiErr.EndpointError != nil &&
+			apiErr.EndpointError.Tag == files.ExportErrorNonExportable {
+			return nil, nil, ErrNonExportable
+		}
+		return nil, nil, fmt.Errorf("export failed: %w", err)
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read exported content: %w", err)
+	}
+
+	if res.FileMetadata != nil {
+		c.unscopeMetadata(res.FileMetadata)
+	}
+	return res, data, nil
+}
+
+// ErrNotTeamToken is returned by team operations when the access token
+// belongs to a single account rather than a Dropbox Business team.
+var ErrNotTeamToken = errors.New("this operation requires a team-scoped token from a Dropbox Business app")
+
+func (c *Client) ListTeamMembers(includeRemoved bool) ([]*team.TeamMemberInfoV2, error) {
+	arg := team.NewMembersListArg()
+	arg.IncludeRemoved = includeRemoved
+
+	res, err := c.teamClient.MembersListV2(arg)
+	if err != nil {
+		// User tokens are rejected by team endpoints with a 400 response
+		var badRequest dbxauth.BadRequest
+		if errors.As(err, &badRequest) {
+			return nil, ErrNotTeamToken
+		}
+		return nil, fmt.Errorf("failed to list team members: %w", err)
+	}
+
+	members := res.Members
+	for res.HasMore {
+		arg := team.NewMembersListContinueArg(res.Cursor)
+		res, err = c.teamClient.MembersListContinueV2(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to continue listing team members: %w", err)
+		}
+		members = append(members, res.Members...)
+	}
+
+	return members, nil
 }
 
 func isBase64(s string) bool {
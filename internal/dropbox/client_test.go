@@ -0,0 +1,952 @@
+package dropbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/paper"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
+	"go.ngs.io/dropbox-mcp-server/internal/config"
+)
+
+// fakeFiles implements the methods of files.Client that a test sets. Calling
+// any other method panics on the nil embedded interface.
+type fakeFiles struct {
+	files.Client
+
+	listFolder            func(arg *files.ListFolderArg) (*files.ListFolderResult, error)
+	listFolderContinue    func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error)
+	getMetadata           func(arg *files.GetMetadataArg) (files.IsMetadata, error)
+	download              func(arg *files.DownloadArg) (*files.FileMetadata, io.ReadCloser, error)
+	searchContinueV2      func(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error)
+	copyBatchV2           func(arg *files.RelocationBatchArgBase) (*files.RelocationBatchV2Launch, error)
+	copyReferenceGet      func(arg *files.GetCopyReferenceArg) (*files.GetCopyReferenceResult, error)
+	copyReferenceSave     func(arg *files.SaveCopyReferenceArg) (*files.SaveCopyReferenceResult, error)
+	upload                func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error)
+	uploadSessionStart    func(arg *files.UploadSessionStartArg, content io.Reader) (*files.UploadSessionStartResult, error)
+	uploadSessionAppendV2 func(arg *files.UploadSessionAppendArg, content io.Reader) error
+	uploadSessionFinish   func(arg *files.UploadSessionFinishArg, content io.Reader) (*files.FileMetadata, error)
+}
+
+func (f *fakeFiles) ListFolder(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+	return f.listFolder(arg)
+}
+
+func (f *fakeFiles) ListFolderContinue(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+	return f.listFolderContinue(arg)
+}
+
+func (f *fakeFiles) GetMetadata(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+	return f.getMetadata(arg)
+}
+
+func (f *fakeFiles) Download(arg *files.DownloadArg) (*files.FileMetadata, io.ReadCloser, error) {
+	return f.download(arg)
+}
+
+func (f *fakeFiles) SearchContinueV2(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error) {
+	return f.searchContinueV2(arg)
+}
+
+func (f *fakeFiles) CopyBatchV2(arg *files.RelocationBatchArgBase) (*files.RelocationBatchV2Launch, error) {
+	return f.copyBatchV2(arg)
+}
+
+func (f *fakeFiles) CopyReferenceGet(arg *files.GetCopyReferenceArg) (*files.GetCopyReferenceResult, error) {
+	return f.copyReferenceGet(arg)
+}
+
+func (f *fakeFiles) CopyReferenceSave(arg *files.SaveCopyReferenceArg) (*files.SaveCopyReferenceResult, error) {
+	return f.copyReferenceSave(arg)
+}
+
+func (f *fakeFiles) Upload(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+	return f.upload(arg, content)
+}
+
+func (f *fakeFiles) UploadSessionStart(arg *files.UploadSessionStartArg, content io.Reader) (*files.UploadSessionStartResult, error) {
+	return f.uploadSessionStart(arg, content)
+}
+
+func (f *fakeFiles) UploadSessionAppendV2(arg *files.UploadSessionAppendArg, content io.Reader) error {
+	return f.uploadSessionAppendV2(arg, content)
+}
+
+func (f *fakeFiles) UploadSessionFinish(arg *files.UploadSessionFinishArg, content io.Reader) (*files.FileMetadata, error) {
+	return f.uploadSessionFinish(arg, content)
+}
+
+// fakeSharing implements the methods of sharing.Client that a test sets.
+type fakeSharing struct {
+	sharing.Client
+
+	listSharedLinks   func(arg *sharing.ListSharedLinksArg) (*sharing.ListSharedLinksResult, error)
+	getFolderMetadata func(arg *sharing.GetMetadataArgs) (*sharing.SharedFolderMetadata, error)
+	mountFolder       func(arg *sharing.MountFolderArg) (*sharing.SharedFolderMetadata, error)
+}
+
+func (f *fakeSharing) ListSharedLinks(arg *sharing.ListSharedLinksArg) (*sharing.ListSharedLinksResult, error) {
+	return f.listSharedLinks(arg)
+}
+
+func (f *fakeSharing) MountFolder(arg *sharing.MountFolderArg) (*sharing.SharedFolderMetadata, error) {
+	return f.mountFolder(arg)
+}
+
+func (f *fakeSharing) GetFolderMetadata(arg *sharing.GetMetadataArgs) (*sharing.SharedFolderMetadata, error) {
+	return f.getFolderMetadata(arg)
+}
+
+func newTestClient(filesClient files.Client, sharingClient sharing.Client, basePath string) *Client {
+	return NewClientFromSDK(&config.Config{BasePath: basePath}, filesClient, sharingClient)
+}
+
+func fileEntry(path string, size uint64) *files.FileMetadata {
+	return &files.FileMetadata{
+		Metadata: files.Metadata{
+			Name:        path[strings.LastIndex(path, "/")+1:],
+			PathDisplay: path,
+			PathLower:   strings.ToLower(path),
+		},
+		Size: size,
+	}
+}
+
+func TestListFolderFollowsCursor(t *testing.T) {
+	var continued []string
+	fake := &fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			if arg.Path != "/Docs" {
+				t.Errorf("ListFolder path = %q, want /Docs", arg.Path)
+			}
+			return &files.ListFolderResult{
+				Entries: []files.IsMetadata{fileEntry("/Docs/a.txt", 1)},
+				Cursor:  "c1",
+				HasMore: true,
+			}, nil
+		},
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			continued = append(continued, arg.Cursor)
+			if arg.Cursor == "c1" {
+				return &files.ListFolderResult{
+					Entries: []files.IsMetadata{fileEntry("/Docs/b.txt", 2)},
+					Cursor:  "c2",
+					HasMore: true,
+				}, nil
+			}
+			return &files.ListFolderResult{
+				Entries: []files.IsMetadata{fileEntry("/Docs/c.txt", 3)},
+				Cursor:  "c3",
+			}, nil
+		},
+	}
+
+	entries, cursor, err := newTestClient(fake, nil, "").ListFolderWithCursor("/Docs", false)
+	if err != nil {
+		t.Fatalf("ListFolderWithCursor() error = %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.(*files.FileMetadata).Name)
+	}
+	if got, want := strings.Join(names, ","), "a.txt,b.txt,c.txt"; got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+	if cursor != "c3" {
+		t.Errorf("cursor = %q, want c3", cursor)
+	}
+	if got, want := strings.Join(continued, ","), "c1,c2"; got != want {
+		t.Errorf("continued with cursors %s, want %s", got, want)
+	}
+}
+
+func TestListFolderWrapsErrors(t *testing.T) {
+	apiErr := errors.New("boom")
+
+	tests := []struct {
+		name string
+		fake *fakeFiles
+		want string
+	}{
+		{
+			name: "first page",
+			fake: &fakeFiles{
+				listFolder: func(*files.ListFolderArg) (*files.ListFolderResult, error) {
+					return nil, apiErr
+				},
+			},
+			want: "failed to list folder: boom",
+		},
+		{
+			name: "later page",
+			fake: &fakeFiles{
+				listFolder: func(*files.ListFolderArg) (*files.ListFolderResult, error) {
+					return &files.ListFolderResult{Cursor: "c1", HasMore: true}, nil
+				},
+				listFolderContinue: func(*files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+					return nil, apiErr
+				},
+			},
+			want: "failed to continue listing: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestClient(tt.fake, nil, "").ListFolder("")
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("ListFolder() error = %v, want %q", err, tt.want)
+			}
+			if !errors.Is(err, apiErr) {
+				t.Errorf("ListFolder() error does not wrap the API error")
+			}
+		})
+	}
+}
+
+func TestUploadStreamSmallFileUsesSingleRequest(t *testing.T) {
+	var uploaded string
+	fake := &fakeFiles{
+		upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+			if arg.Path != "/notes.txt" {
+				t.Errorf("Upload path = %q, want /notes.txt", arg.Path)
+			}
+			if arg.Mode == nil || arg.Mode.Tag != files.WriteModeOverwrite {
+				t.Errorf("Upload mode = %v, want overwrite", arg.Mode)
+			}
+			data, err := io.ReadAll(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			uploaded = string(data)
+			return fileEntry(arg.Path, uint64(len(data))), nil
+		},
+	}
+
+	content := "hello"
+	client := newTestClient(fake, nil, "")
+	metadata, err := client.UploadStream("/notes.txt", strings.NewReader(content), int64(len(content)), UploadOptions{Mode: "overwrite"})
+	if err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+	if uploaded != content {
+		t.Errorf("uploaded %q, want %q", uploaded, content)
+	}
+	if metadata.PathDisplay != "/notes.txt" {
+		t.Errorf("PathDisplay = %q, want /notes.txt", metadata.PathDisplay)
+	}
+}
+
+// zeroReader yields an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestUploadStreamLargeFileUsesSession(t *testing.T) {
+	const size = largeUploadThreshold + 1
+
+	var appended uint64
+	var appends int
+	fake := &fakeFiles{
+		uploadSessionStart: func(*files.UploadSessionStartArg, io.Reader) (*files.UploadSessionStartResult, error) {
+			return &files.UploadSessionStartResult{SessionId: "session"}, nil
+		},
+		uploadSessionAppendV2: func(arg *files.UploadSessionAppendArg, content io.Reader) error {
+			if arg.Cursor.SessionId != "session" {
+				t.Errorf("append session = %q, want session", arg.Cursor.SessionId)
+			}
+			if arg.Cursor.Offset != appended {
+				t.Errorf("append offset = %d, want %d", arg.Cursor.Offset, appended)
+			}
+			n, err := io.Copy(io.Discard, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n > 4*1024*1024 {
+				t.Errorf("chunk of %d bytes exceeds 4MB", n)
+			}
+			appended += uint64(n)
+			appends++
+			return nil
+		},
+		uploadSessionFinish: func(arg *files.UploadSessionFinishArg, _ io.Reader) (*files.FileMetadata, error) {
+			if arg.Cursor.Offset != size {
+				t.Errorf("finish offset = %d, want %d", arg.Cursor.Offset, size)
+			}
+			if arg.Commit.Path != "/big.bin" {
+				t.Errorf("commit path = %q, want /big.bin", arg.Commit.Path)
+			}
+			return fileEntry(arg.Commit.Path, arg.Cursor.Offset), nil
+		},
+	}
+
+	r := io.LimitReader(zeroReader{}, size)
+	if _, err := newTestClient(fake, nil, "").UploadStream("/big.bin", r, size, UploadOptions{}); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+	if appended != size {
+		t.Errorf("appended %d bytes, want %d", appended, size)
+	}
+	if appends < 2 {
+		t.Errorf("appended in %d chunks, want several", appends)
+	}
+}
+
+func TestUploadStreamCommitInfoAroundThreshold(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 10, 30, 15, 500, time.UTC)
+	opts := UploadOptions{Mode: "update", Rev: "015f", ClientModified: &modified, FailOnConflict: true}
+
+	for _, tt := range []struct {
+		size    int64
+		session bool
+	}{
+		{largeUploadThreshold - 1, false},
+		{largeUploadThreshold, false},
+		{largeUploadThreshold + 1, true},
+	} {
+		var commit *files.CommitInfo
+		var session bool
+		fake := &fakeFiles{
+			upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+				commit = &arg.CommitInfo
+				n, err := io.Copy(io.Discard, content)
+				return fileEntry(arg.Path, uint64(n)), err
+			},
+			uploadSessionStart: func(*files.UploadSessionStartArg, io.Reader) (*files.UploadSessionStartResult, error) {
+				session = true
+				return &files.UploadSessionStartResult{SessionId: "session"}, nil
+			},
+			uploadSessionAppendV2: func(_ *files.UploadSessionAppendArg, content io.Reader) error {
+				_, err := io.Copy(io.Discard, content)
+				return err
+			},
+			uploadSessionFinish: func(arg *files.UploadSessionFinishArg, _ io.Reader) (*files.FileMetadata, error) {
+				commit = arg.Commit
+				return fileEntry(arg.Commit.Path, arg.Cursor.Offset), nil
+			},
+		}
+
+		r := io.LimitReader(zeroReader{}, tt.size)
+		if _, err := newTestClient(fake, nil, "").UploadStream("/big.bin", r, tt.size, opts); err != nil {
+			t.Fatalf("UploadStream(%d bytes) error = %v", tt.size, err)
+		}
+		if session != tt.session {
+			t.Errorf("UploadStream(%d bytes) used a session = %v, want %v", tt.size, session, tt.session)
+		}
+		if commit == nil {
+			t.Fatalf("UploadStream(%d bytes) did not commit", tt.size)
+		}
+		if commit.Mode == nil || commit.Mode.Tag != files.WriteModeUpdate || commit.Mode.Update != "015f" {
+			t.Errorf("UploadStream(%d bytes) mode = %+v, want update of 015f", tt.size, commit.Mode)
+		}
+		if commit.Autorename {
+			t.Errorf("UploadStream(%d bytes) autorename = true, want false", tt.size)
+		}
+		if commit.ClientModified == nil || !commit.ClientModified.Equal(modified.Truncate(time.Second)) {
+			t.Errorf("UploadStream(%d bytes) client_modified = %v, want %v", tt.size, commit.ClientModified, modified.Truncate(time.Second))
+		}
+	}
+}
+
+func TestBasePathScopesArguments(t *testing.T) {
+	fake := &fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			if arg.Path != "/App/a.txt" {
+				t.Errorf("GetMetadata path = %q, want /App/a.txt", arg.Path)
+			}
+			return fileEntry("/App/a.txt", 1), nil
+		},
+	}
+	client := newTestClient(fake, nil, "/App")
+
+	metadata, err := client.GetMetadata("/a.txt")
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if got := metadata.(*files.FileMetadata).PathDisplay; got != "/a.txt" {
+		t.Errorf("PathDisplay = %q, want /a.txt", got)
+	}
+
+	for _, path := range []string{"id:abc", "ns:123/a.txt", "/../a.txt"} {
+		if _, err := client.GetMetadata(path); err == nil {
+			t.Errorf("GetMetadata(%q) succeeded, want error", path)
+		}
+	}
+}
+
+func TestDownloadRevisionChecksBasePath(t *testing.T) {
+	fake := &fakeFiles{
+		download: func(arg *files.DownloadArg) (*files.FileMetadata, io.ReadCloser, error) {
+			path := map[string]string{"rev:inside": "/app/a.txt", "rev:outside": "/other/secret.txt"}[arg.Path]
+			return fileEntry(path, 4), io.NopCloser(strings.NewReader("data")), nil
+		},
+	}
+	client := newTestClient(fake, nil, "/App")
+
+	data, err := client.DownloadRevision("/a.txt", "inside")
+	if err != nil {
+		t.Fatalf("DownloadRevision(inside) error = %v", err)
+	}
+	if string(data) != "data" {
+		t.Errorf("DownloadRevision(inside) = %q, want data", data)
+	}
+
+	if _, err := client.DownloadRevision("/a.txt", "outside"); err == nil {
+		t.Error("DownloadRevision(outside) succeeded, want error")
+	}
+}
+
+func TestDownloadToFileRemovesStalePartials(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "report.txt")
+	hash, err := ContentHash(strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			file := fileEntry(arg.Path, 4)
+			file.Rev = "0200"
+			file.ContentHash = hash
+			return file, nil
+		},
+		download: func(arg *files.DownloadArg) (*files.FileMetadata, io.ReadCloser, error) {
+			return nil, io.NopCloser(strings.NewReader("data")), nil
+		},
+	}
+	client := NewClientFromSDK(&config.Config{TempDir: tempDir}, fake, nil)
+
+	stale := client.partialPath(target, "0100")
+	other := client.partialPath(filepath.Join(filepath.Dir(target), "other.txt"), "0100")
+	for _, name := range []string{stale, other} {
+		if err := os.WriteFile(name, []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := client.DownloadToFile("/report.txt", target); err != nil {
+		t.Fatalf("DownloadToFile() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("partial file of the older revision was kept: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("partial file of another target was removed: %v", err)
+	}
+}
+
+func TestCopyBatchDedup(t *testing.T) {
+	var saved []string
+	fake := &fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			file := fileEntry(arg.Path, 4)
+			file.ContentHash = "same"
+			return file, nil
+		},
+		copyReferenceGet: func(arg *files.GetCopyReferenceArg) (*files.GetCopyReferenceResult, error) {
+			return &files.GetCopyReferenceResult{CopyReference: "ref"}, nil
+		},
+		copyReferenceSave: func(arg *files.SaveCopyReferenceArg) (*files.SaveCopyReferenceResult, error) {
+			saved = append(saved, arg.Path)
+			return &files.SaveCopyReferenceResult{Metadata: fileEntry(arg.Path, 4)}, nil
+		},
+		copyBatchV2: func(arg *files.RelocationBatchArgBase) (*files.RelocationBatchV2Launch, error) {
+			return nil, errors.New("internal server error")
+		},
+	}
+	client := newTestClient(fake, nil, "/App")
+	opts := CopyBatchOptions{Dedup: true}
+
+	// An invalid entry fails the batch before anything is copied
+	_, err := client.CopyBatch([]RelocationEntry{
+		{FromPath: "/a.txt", ToPath: "/x/a.txt"},
+		{FromPath: "/b.txt", ToPath: "/x/b.txt"},
+		{FromPath: "/c.txt", ToPath: "/../c.txt"},
+	}, opts)
+	if err == nil {
+		t.Fatal("CopyBatch() with an invalid entry succeeded, want error")
+	}
+	if len(saved) != 0 {
+		t.Fatalf("saved %v before validating the batch", saved)
+	}
+
+	// Copies already saved are reported when the batch cannot be started
+	status, err := client.CopyBatch([]RelocationEntry{
+		{FromPath: "/a.txt", ToPath: "/x/a.txt"},
+		{FromPath: "/b.txt", ToPath: "/x/b.txt"},
+	}, opts)
+	if err != nil {
+		t.Fatalf("CopyBatch() error = %v", err)
+	}
+	if status.Status != JobFailed || !strings.Contains(status.Error, "internal server error") {
+		t.Errorf("CopyBatch() status = %q, error = %q, want a failed launch", status.Status, status.Error)
+	}
+	if len(status.Deduplicated) != 1 || status.Deduplicated[0].ToPath != "/x/b.txt" || status.Deduplicated[0].Error != "" {
+		t.Errorf("CopyBatch() deduplicated = %+v, want the copy to /x/b.txt", status.Deduplicated)
+	}
+}
+
+func TestContinuedListingsStayInBasePath(t *testing.T) {
+	fake := &fakeFiles{
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			return &files.ListFolderResult{
+				Entries: []files.IsMetadata{fileEntry("/App/a.txt", 1), fileEntry("/Other/b.txt", 2)},
+			}, nil
+		},
+		searchContinueV2: func(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error) {
+			match := func(path string) *files.SearchMatchV2 {
+				return &files.SearchMatchV2{Metadata: &files.MetadataV2{Metadata: fileEntry(path, 1)}}
+			}
+			return &files.SearchV2Result{Matches: []*files.SearchMatchV2{match("/Other/c.txt"), match("/App/d.txt")}}, nil
+		},
+	}
+	client := newTestClient(fake, nil, "/App")
+
+	entries, _, err := client.ListFolderContinuePage("cursor-for-another-folder")
+	if err != nil {
+		t.Fatalf("ListFolderContinuePage() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].(*files.FileMetadata).PathDisplay != "/a.txt" {
+		t.Errorf("ListFolderContinuePage() = %v, want only /a.txt", entries)
+	}
+
+	entries, _, err = client.ListFolderChanges("cursor-for-another-folder")
+	if err != nil {
+		t.Fatalf("ListFolderChanges() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("ListFolderChanges() returned %d entries, want 1", len(entries))
+	}
+
+	matches, _, err := client.SearchContinue("cursor-for-another-search")
+	if err != nil {
+		t.Fatalf("SearchContinue() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Metadata.Metadata.(*files.FileMetadata).PathDisplay != "/d.txt" {
+		t.Errorf("SearchContinue() = %v, want only /d.txt", matches)
+	}
+}
+
+func TestListSharedLinksPagesAndFiltersBasePath(t *testing.T) {
+	link := func(path string) sharing.IsSharedLinkMetadata {
+		return &sharing.FileLinkMetadata{
+			SharedLinkMetadata: sharing.SharedLinkMetadata{
+				Url:       "https://www.dropbox.com/s/" + path,
+				PathLower: path,
+			},
+		}
+	}
+
+	fake := &fakeSharing{
+		listSharedLinks: func(arg *sharing.ListSharedLinksArg) (*sharing.ListSharedLinksResult, error) {
+			if arg.Cursor == "" {
+				return &sharing.ListSharedLinksResult{
+					Links:   []sharing.IsSharedLinkMetadata{link("/app/a.txt"), link("/other/b.txt")},
+					HasMore: true,
+					Cursor:  "c1",
+				}, nil
+			}
+			return &sharing.ListSharedLinksResult{
+				Links: []sharing.IsSharedLinkMetadata{link("/app/c.txt")},
+			}, nil
+		},
+	}
+
+	links, err := newTestClient(nil, fake, "/App").ListSharedLinks("", false)
+	if err != nil {
+		t.Fatalf("ListSharedLinks() error = %v", err)
+	}
+
+	var paths []string
+	for _, l := range links {
+		paths = append(paths, l.(*sharing.FileLinkMetadata).PathLower)
+	}
+	if got, want := strings.Join(paths, ","), "/a.txt,/c.txt"; got != want {
+		t.Errorf("links = %s, want %s", got, want)
+	}
+}
+
+func TestGetSharedFolderMetadata(t *testing.T) {
+	fake := &fakeSharing{
+		getFolderMetadata: func(arg *sharing.GetMetadataArgs) (*sharing.SharedFolderMetadata, error) {
+			switch arg.SharedFolderId {
+			case "84528192421":
+				folder := &sharing.SharedFolderMetadata{Name: "Team", SharedFolderId: arg.SharedFolderId}
+				folder.PathLower = "/app/team"
+				return folder, nil
+			case "84528192422":
+				folder := &sharing.SharedFolderMetadata{Name: "Other", SharedFolderId: arg.SharedFolderId}
+				folder.PathLower = "/other"
+				return folder, nil
+			}
+			return nil, sharing.GetFolderMetadataAPIError{
+				EndpointError: &sharing.SharedFolderAccessError{Tagged: dropbox.Tagged{Tag: sharing.SharedFolderAccessErrorInvalidId}},
+			}
+		},
+	}
+	client := newTestClient(nil, fake, "/App")
+
+	folder, err := client.GetSharedFolderMetadata("84528192421")
+	if err != nil {
+		t.Fatalf("GetSharedFolderMetadata() error = %v", err)
+	}
+	if folder.PathLower != "/team" {
+		t.Errorf("PathLower = %q, want /team", folder.PathLower)
+	}
+
+	if _, err := client.GetSharedFolderMetadata("84528192422"); err == nil || !strings.Contains(err.Error(), "outside the base path") {
+		t.Errorf("GetSharedFolderMetadata() outside the base path error = %v", err)
+	}
+	if _, err := client.GetSharedFolderMetadata("nope"); err == nil || !strings.Contains(err.Error(), "nope is not a shared folder ID") {
+		t.Errorf("GetSharedFolderMetadata() of an invalid ID error = %v", err)
+	}
+}
+
+func TestMountFolder(t *testing.T) {
+	fake := &fakeSharing{
+		mountFolder: func(arg *sharing.MountFolderArg) (*sharing.SharedFolderMetadata, error) {
+			if arg.SharedFolderId == "84528192421" {
+				folder := &sharing.SharedFolderMetadata{Name: "Team", SharedFolderId: arg.SharedFolderId}
+				folder.PathLower = "/team"
+				return folder, nil
+			}
+			return nil, sharing.MountFolderAPIError{
+				EndpointError: &sharing.MountFolderError{Tagged: dropbox.Tagged{Tag: sharing.MountFolderErrorAlreadyMounted}},
+			}
+		},
+	}
+
+	folder, err := newTestClient(nil, fake, "").MountFolder("84528192421")
+	if err != nil || folder.PathLower != "/team" {
+		t.Errorf("MountFolder() = %v, %v, want the folder mounted at /team", folder, err)
+	}
+	if _, err := newTestClient(nil, fake, "").MountFolder("84528192422"); err == nil || !strings.Contains(err.Error(), "already mounted") {
+		t.Errorf("MountFolder() of a mounted folder error = %v", err)
+	}
+	if _, err := newTestClient(nil, fake, "/App").MountFolder("84528192421"); err == nil {
+		t.Error("MountFolder() with a base path succeeded, want an error")
+	}
+}
+
+func TestFolderSizeBySubfolder(t *testing.T) {
+	folder := func(path string) *files.FolderMetadata {
+		return &files.FolderMetadata{Metadata: files.Metadata{
+			Name:        path[strings.LastIndex(path, "/")+1:],
+			PathDisplay: path,
+			PathLower:   strings.ToLower(path),
+		}}
+	}
+	fake := &fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			if arg.Path != "/base/Photos" || !arg.Recursive {
+				t.Errorf("ListFolder(%q, recursive %t), want a recursive listing of /base/Photos", arg.Path, arg.Recursive)
+			}
+			return &files.ListFolderResult{
+				Entries: []files.IsMetadata{
+					folder("/base/Photos"),
+					folder("/base/Photos/2023"),
+					fileEntry("/base/Photos/2023/a.jpg", 100),
+					fileEntry("/base/Photos/cover.jpg", 5),
+				},
+				Cursor:  "c1",
+				HasMore: true,
+			}, nil
+		},
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			return &files.ListFolderResult{Entries: []files.IsMetadata{
+				folder("/base/Photos/2023/Trip"),
+				fileEntry("/base/Photos/2023/Trip/b.jpg", 200),
+				fileEntry("/base/Photos/2024/c.jpg", 50),
+			}}, nil
+		},
+	}
+
+	total, subfolders, err := newTestClient(fake, nil, "/base").FolderSize("/Photos", true)
+	if err != nil {
+		t.Fatalf("FolderSize() error = %v", err)
+	}
+
+	if want := (FolderSize{Files: 4, Folders: 2, Size: 355}); *total != want {
+		t.Errorf("total = %+v, want %+v", *total, want)
+	}
+	want := []FolderSize{
+		{Name: "2023", Path: "/Photos/2023", Files: 2, Folders: 1, Size: 300},
+		{Name: "2024", Path: "/Photos/2024", Files: 1, Size: 50},
+	}
+	if len(subfolders) != len(want) {
+		t.Fatalf("got %d subfolders, want %d", len(subfolders), len(want))
+	}
+	for i := range want {
+		if *subfolders[i] != want[i] {
+			t.Errorf("subfolders[%d] = %+v, want %+v", i, *subfolders[i], want[i])
+		}
+	}
+}
+
+func TestHTTPClientSetsUserAgent(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	for _, cfg := range []*config.Config{{}, {UserAgent: "acme-agent/2.0"}} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := newHTTPClient(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if want := []string{DefaultUserAgent, "acme-agent/2.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("User-Agent = %q, want %q", got, want)
+	}
+}
+
+func TestHTTPClientRecordsFailures(t *testing.T) {
+	const body = `{"error_summary": "to/conflict/file/..", "error": {".tag": "to"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Dropbox-Request-Id", "abc123")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	ctx, failures := WithFailureLog(context.Background())
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/2/files/move_v2", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newHTTPClient(ctx, &config.Config{AccessToken: "secret-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The SDK still has to be able to decode the error
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != body {
+		t.Errorf("response body = %q, %v, want it unchanged", data, err)
+	}
+
+	failure := failures.Last()
+	if failure == nil {
+		t.Fatal("Last() = nil, want the failed response")
+	}
+	if failure.RequestID != "abc123" || failure.StatusCode != http.StatusConflict || failure.Body != body {
+		t.Errorf("Last() = %+v, want request ID, status and body of the response", failure)
+	}
+	if got := failure.RequestHeaders["Authorization"]; got != "REDACTED" {
+		t.Errorf("Authorization header = %q, want it redacted", got)
+	}
+}
+
+func TestHTTPClientUsesConfiguredProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+	}))
+	defer proxy.Close()
+
+	client, err := newHTTPClient(context.Background(), &config.Config{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "http://api.dropboxapi.test/2/check/user", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if want := []string{"api.dropboxapi.test"}; !reflect.DeepEqual(proxied, want) {
+		t.Errorf("proxied hosts = %v, want %v", proxied, want)
+	}
+}
+
+func TestValidateTokenUsesConfiguredProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Host)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	if err := ValidateToken(&config.Config{AccessToken: "secret-token", ProxyURL: proxy.URL}); err == nil {
+		t.Error("ValidateToken() through a refusing proxy succeeded, want an error")
+	}
+	if want := []string{"api.dropboxapi.com:443"}; !reflect.DeepEqual(proxied, want) {
+		t.Errorf("proxied hosts = %v, want %v", proxied, want)
+	}
+}
+
+func TestHTTPClientProxyErrors(t *testing.T) {
+	if _, err := newHTTPClient(context.Background(), &config.Config{ProxyURL: "ftp://proxy.example.com"}); err == nil {
+		t.Error("newHTTPClient() with an ftp proxy succeeded, want an error")
+	}
+
+	// Nothing listens on the proxy once the server is closed
+	proxy := httptest.NewServer(http.NotFoundHandler())
+	proxy.Close()
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+
+	client, err := newHTTPClient(context.Background(), &config.Config{ProxyURL: proxyURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "http://api.dropboxapi.test/2/check/user", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request through a closed proxy succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "through proxy") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("error = %v, want it to name the proxy without its password", err)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBreakerTransport(t *testing.T) {
+	clock := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	status := http.StatusServiceUnavailable
+	var sent int
+	transport := &breakerTransport{
+		breaker: &circuitBreaker{threshold: 3, cooldown: 30 * time.Second, now: func() time.Time { return clock }},
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+		}),
+	}
+	send := func() error {
+		req := httptest.NewRequest(http.MethodPost, "https://api.dropboxapi.com/2/files/get_metadata", http.NoBody)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := send(); err != nil {
+			t.Fatalf("request %d error = %v, want the server error passed through", i, err)
+		}
+	}
+	if err := send(); !errors.Is(err, ErrServiceUnavailable) || sent != 3 {
+		t.Fatalf("request after 3 failures error = %v, sent %d, want a fast service unavailable error", err, sent)
+	}
+
+	// After the cooldown a failing probe opens the breaker again
+	clock = clock.Add(31 * time.Second)
+	if err := send(); err != nil || sent != 4 {
+		t.Fatalf("probe error = %v, sent %d, want the probe sent", err, sent)
+	}
+	if err := send(); !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("request after a failed probe error = %v, want the breaker open", err)
+	}
+
+	// A successful probe closes it
+	clock = clock.Add(31 * time.Second)
+	status = http.StatusConflict
+	for i := 0; i < 3; i++ {
+		if err := send(); err != nil {
+			t.Fatalf("request %d after recovery error = %v", i, err)
+		}
+	}
+	if sent != 7 {
+		t.Errorf("sent %d requests, want every request after recovery sent", sent)
+	}
+}
+
+// fakePaper implements the methods of paper.Client that a test sets.
+type fakePaper struct {
+	paper.Client
+
+	docsList         func(arg *paper.ListPaperDocsArgs) (*paper.ListPaperDocsResponse, error)
+	docsListContinue func(arg *paper.ListPaperDocsContinueArgs) (*paper.ListPaperDocsResponse, error)
+}
+
+func (f *fakePaper) DocsList(arg *paper.ListPaperDocsArgs) (*paper.ListPaperDocsResponse, error) {
+	return f.docsList(arg)
+}
+
+func (f *fakePaper) DocsListContinue(arg *paper.ListPaperDocsContinueArgs) (*paper.ListPaperDocsResponse, error) {
+	return f.docsListContinue(arg)
+}
+
+func TestListPaperDocs(t *testing.T) {
+	client := NewClientFromSDK(&config.Config{}, nil, nil)
+	client.paperClient = &fakePaper{
+		docsList: func(arg *paper.ListPaperDocsArgs) (*paper.ListPaperDocsResponse, error) {
+			if arg.FilterBy.Tag != paper.ListPaperDocsFilterByDocsCreated || arg.Limit != 3 {
+				t.Errorf("DocsList() filter_by = %s, limit = %d, want docs_created and 3", arg.FilterBy.Tag, arg.Limit)
+			}
+			return &paper.ListPaperDocsResponse{DocIds: []string{"a", "b"}, HasMore: true, Cursor: &paper.Cursor{Value: "next"}}, nil
+		},
+		docsListContinue: func(arg *paper.ListPaperDocsContinueArgs) (*paper.ListPaperDocsResponse, error) {
+			return &paper.ListPaperDocsResponse{DocIds: []string{"c", "d"}, HasMore: true, Cursor: &paper.Cursor{Value: "more"}}, nil
+		},
+	}
+
+	docIDs, err := client.ListPaperDocs(PaperListOptions{FilterBy: paper.ListPaperDocsFilterByDocsCreated, Limit: 3})
+	if err != nil {
+		t.Fatalf("ListPaperDocs() error = %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(docIDs, want) {
+		t.Errorf("ListPaperDocs() = %v, want %v", docIDs, want)
+	}
+
+	client.paperClient = &fakePaper{
+		docsList: func(arg *paper.ListPaperDocsArgs) (*paper.ListPaperDocsResponse, error) {
+			return nil, paper.DocsListAPIError{APIError: dropbox.APIError{ErrorSummary: "insufficient_permissions/.."}}
+		},
+	}
+	if _, err := client.ListPaperDocs(PaperListOptions{}); !errors.Is(err, ErrPaperUnavailable) {
+		t.Errorf("ListPaperDocs() error = %v, want ErrPaperUnavailable", err)
+	}
+
+	scoped := NewClientFromSDK(&config.Config{BasePath: "/Work"}, nil, nil)
+	if _, err := scoped.ListPaperDocs(PaperListOptions{}); err == nil {
+		t.Error("ListPaperDocs() with a base path succeeded, want an error")
+	}
+}
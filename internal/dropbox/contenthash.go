@@ -0,0 +1,48 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// contentHashBlockSize is the block size used by the Dropbox content hash.
+const contentHashBlockSize = 4 * 1024 * 1024
+
+// ContentHash computes the Dropbox content hash of r: the SHA-256 of the
+// concatenated SHA-256 digests of each 4MB block. It matches the
+// content_hash field of file metadata.
+// See https://www.dropbox.com/developers/reference/content-hash
+func ContentHash(r io.Reader) (string, error) {
+	overall := sha256.New()
+	block := make([]byte, contentHashBlockSize)
+
+	for {
+		n, err := io.ReadFull(r, block)
+		if n > 0 {
+			sum := sha256.Sum256(block[:n])
+			overall.Write(sum[:])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	return hex.EncodeToString(overall.Sum(nil)), nil
+}
+
+// FileContentHash computes the Dropbox content hash of a local file.
+func FileContentHash(name string) (string, error) {
+	f, err := os.Open(name) // #nosec G304 - path is provided by the user on purpose
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return ContentHash(f)
+}
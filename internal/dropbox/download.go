@@ -0,0 +1,241 @@
+package dropbox
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// downloadAttempts is how many times DownloadToFile tries to fetch the rest of
+// a file before giving up. Every attempt resumes where the last one stopped.
+const downloadAttempts = 3
+
+// DownloadToFile downloads the file at path into the local file target. The
+// content is written to a partial file in the configured temp directory
+// first, which is moved into place once its content hash matches the file's.
+// A download that fails part way leaves the partial file behind, and the next
+// download of the same revision to target resumes from its length with a
+// Range request instead of starting over. Partial files of other revisions
+// for target are removed.
+func (c *Client) DownloadToFile(path, target string) (*files.FileMetadata, error) {
+	metadata, err := c.GetMetadata(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	file, ok := metadata.(*files.FileMetadata)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+
+	partial := c.partialPath(target, file.Rev)
+	c.removeStalePartials(target, partial)
+	for attempt := 1; ; attempt++ {
+		err = c.downloadRemaining(file, partial)
+		if err == nil {
+			break
+		}
+		if attempt == downloadAttempts {
+			return nil, err
+		}
+	}
+
+	hash, err := FileContentHash(partial)
+	if err != nil {
+		return nil, err
+	}
+	if hash != file.ContentHash {
+		os.Remove(partial)
+		return nil, fmt.Errorf("downloaded content of %s does not match its content hash", file.PathDisplay)
+	}
+
+	if err := MoveFile(partial, target); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	return file, nil
+}
+
+// partialPath names the file a download of revision rev into target is
+// written to. Hashing target keeps downloads to different local files apart
+// in the shared temp directory, and including the revision keeps a partial
+// download of an older revision from being resumed with newer content.
+func (c *Client) partialPath(target, rev string) string {
+	return filepath.Join(c.config.TempDirectory(), partialPrefix(target)+rev+".partial")
+}
+
+// partialPrefix returns the start of the names of partial files for target,
+// which is shared by all revisions.
+func partialPrefix(target string) string {
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	sum := sha256.Sum256([]byte(target))
+	return fmt.Sprintf("dropbox-%x-", sum[:8])
+}
+
+// removeStalePartials removes the partial files left by interrupted downloads
+// of other revisions into target, which can no longer be resumed once the
+// file has changed. Errors are ignored, as stale files only waste space.
+func (c *Client) removeStalePartials(target, partial string) {
+	dir := c.config.TempDirectory()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	prefix := partialPrefix(target)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".partial") && name != filepath.Base(partial) {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+// MoveFile moves the local file from to to. The temp directory may be on a
+// different file system than to, in which case the file is copied instead.
+func MoveFile(from, to string) error {
+	err := os.Rename(from, to)
+	var linkErr *os.LinkError
+	if err == nil || !errors.As(err, &linkErr) || !errors.Is(linkErr.Err, syscall.EXDEV) {
+		return err
+	}
+
+	src, err := os.Open(from) // #nosec G304 - path is a partial file this server created
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to) // #nosec G304 - path is derived from the requested local path
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
+
+// downloadRemaining appends the part of file that is missing from partial.
+func (c *Client) downloadRemaining(file *files.FileMetadata, partial string) error {
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) // #nosec G304 - path is a partial file in the temp directory
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %w", err)
+	}
+	offset := info.Size()
+	if uint64(offset) == file.Size { // #nosec G115 - file sizes are non-negative
+		return nil
+	}
+	if uint64(offset) > file.Size { // #nosec G115 - file sizes are non-negative
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to reset partial file: %w", err)
+		}
+		offset = 0
+	}
+
+	// The revision is requested explicitly so that every range comes from
+	// the same content, even if the file changes in the meantime.
+	arg := files.NewDownloadArg("rev:" + file.Rev)
+	if offset > 0 {
+		arg.ExtraHeaders = map[string]string{"Range": fmt.Sprintf("bytes=%d-", offset)}
+	}
+
+	_, content, err := c.filesClient.Download(arg)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer content.Close()
+
+	if _, err := io.Copy(f, content); err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// DownloadRange downloads length bytes of the file at path starting at
+// offset, or everything from offset on when length is zero. Fewer bytes are
+// returned when the range extends past the end of the file.
+func (c *Client) DownloadRange(path string, offset, length int64) ([]byte, error) {
+	path, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += fmt.Sprint(offset + length - 1)
+	}
+
+	arg := files.NewDownloadArg(path)
+	arg.ExtraHeaders = map[string]string{"Range": byteRange}
+
+	_, content, err := c.filesClient.Download(arg)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	return data, nil
+}
+
+// DownloadZip downloads the folder at path as a zip archive and writes it to
+// w. Dropbox only zips folders of up to 20 GB and 10,000 files; larger
+// folders fail with an error that suggests syncing them instead.
+func (c *Client) DownloadZip(path string, w io.Writer) (*files.FolderMetadata, error) {
+	scoped, err := c.scopePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res, content, err := c.filesClient.DownloadZip(files.NewDownloadZipArg(scoped))
+	if err != nil {
+		var zipErr files.DownloadZipAPIError
+		if errors.As(err, &zipErr) && zipErr.EndpointError != nil {
+			switch zipErr.EndpointError.Tag {
+			case files.DownloadZipErrorTooLarge, files.DownloadZipErrorTooManyFiles:
+				return nil, fmt.Errorf("%s is too large to download as a zip archive (%s): Dropbox zips folders of up to 20 GB "+
+					"and 10,000 files, use dropbox_sync_down to download it file by file", path, zipErr.EndpointError.Tag)
+			}
+		}
+		return nil, fmt.Errorf("zip download failed: %w", err)
+	}
+	defer content.Close()
+
+	if _, err := io.Copy(w, content); err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	if res.Metadata != nil {
+		c.unscopeMetadata(res.Metadata)
+	}
+	return res.Metadata, nil
+}
@@ -0,0 +1,115 @@
+package dropbox
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxFailureBody caps how much of a failed response body is kept.
+const maxFailureBody = 64 * 1024
+
+// redactedHeaders are request headers whose values are never recorded.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// APIFailure is a failed response from the Dropbox API. The SDK decodes
+// error responses into typed errors that keep little more than the error
+// summary, so the raw response is recorded for debugging.
+type APIFailure struct {
+	// RequestID is the X-Dropbox-Request-Id header, which Dropbox support
+	// needs to look up a request.
+	RequestID  string `json:"request_id,omitempty"`
+	StatusCode int    `json:"status_code"`
+	// Endpoint is the URL of the request, such as
+	// https://api.dropboxapi.com/2/files/move_v2.
+	Endpoint string `json:"endpoint"`
+	// RequestHeaders holds the request headers with credentials redacted.
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	// Body is the response body, usually a JSON error document.
+	Body string `json:"body,omitempty"`
+}
+
+// FailureLog collects the failed API responses of a tool call.
+type FailureLog struct {
+	mu       sync.Mutex
+	failures []APIFailure
+}
+
+// Last returns the most recent failure, or nil if every request succeeded.
+func (l *FailureLog) Last() *APIFailure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.failures) == 0 {
+		return nil
+	}
+	failure := l.failures[len(l.failures)-1]
+	return &failure
+}
+
+func (l *FailureLog) add(failure APIFailure) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures = append(l.failures, failure)
+}
+
+type failureLogKey struct{}
+
+// WithFailureLog returns a context in which clients record failed API
+// responses in the returned log.
+func WithFailureLog(ctx context.Context) (context.Context, *FailureLog) {
+	failures := &FailureLog{}
+	return context.WithValue(ctx, failureLogKey{}, failures), failures
+}
+
+// failureTransport records failed responses in the FailureLog of the request
+// context, leaving the response body intact for the SDK to decode.
+type failureTransport struct {
+	base http.RoundTripper
+}
+
+func (t *failureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+
+	failures, ok := req.Context().Value(failureLogKey{}).(*FailureLog)
+	if !ok {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFailureBody))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	failures.add(APIFailure{
+		RequestID:      resp.Header.Get("X-Dropbox-Request-Id"),
+		StatusCode:     resp.StatusCode,
+		Endpoint:       req.URL.String(),
+		RequestHeaders: redactHeaders(req.Header),
+		Body:           string(body),
+	})
+	return resp, nil
+}
+
+// redactHeaders flattens headers, replacing credentials with "REDACTED".
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name := range header {
+		headers[name] = header.Get(name)
+	}
+	for _, name := range redactedHeaders {
+		if _, ok := headers[name]; ok {
+			headers[name] = "REDACTED"
+		}
+	}
+	return headers
+}
@@ -0,0 +1,128 @@
+package dropbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// FolderSize is the number of files and folders below a folder and the total
+// size of the files.
+type FolderSize struct {
+	// Name and Path identify a subfolder. They are empty for the folder
+	// that was measured.
+	Name    string
+	Path    string
+	Files   int
+	Folders int
+	Size    uint64
+}
+
+func (s *FolderSize) add(entry files.IsMetadata) {
+	switch e := entry.(type) {
+	case *files.FileMetadata:
+		s.Files++
+		s.Size += e.Size
+	case *files.FolderMetadata:
+		s.Folders++
+	}
+}
+
+// FolderSize adds up everything below path, one listing page at a time so
+// that the entries of large folders are never held in memory together. With
+// bySubfolder set, the totals of every immediate subfolder are returned too.
+func (c *Client) FolderSize(path string, bySubfolder bool) (*FolderSize, []*FolderSize, error) {
+	scoped, err := c.scopePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	arg := files.NewListFolderArg(scoped)
+	arg.Recursive = true
+	arg.IncludeDeleted = false
+
+	res, err := c.filesClient.ListFolder(arg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list folder: %w", err)
+	}
+
+	// Entries are matched against the folder's path, which IDs and
+	// namespace paths have to be resolved to first
+	folderPath := scoped
+	if kind, _ := ParsePath(path); kind != PathKindDisplay {
+		metadata, err := c.GetMetadata(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get metadata: %w", err)
+		}
+		folder, ok := metadata.(*files.FolderMetadata)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is not a folder", path)
+		}
+		folderPath = folder.PathLower
+	}
+
+	prefix := strings.ToLower(strings.TrimSuffix(folderPath, "/")) + "/"
+	depth := strings.Count(prefix, "/")
+	total := &FolderSize{}
+	var subfolders []*FolderSize
+	byName := map[string]*FolderSize{}
+
+	for {
+		for _, entry := range res.Entries {
+			var pathLower, pathDisplay string
+			switch e := entry.(type) {
+			case *files.FileMetadata:
+				pathLower, pathDisplay = e.PathLower, e.PathDisplay
+			case *files.FolderMetadata:
+				pathLower, pathDisplay = e.PathLower, e.PathDisplay
+			default:
+				continue
+			}
+
+			// A recursive listing includes the folder itself
+			if !strings.HasPrefix(pathLower, prefix) {
+				continue
+			}
+			total.add(entry)
+			if !bySubfolder {
+				continue
+			}
+
+			rel := pathLower[len(prefix):]
+			i := strings.Index(rel, "/")
+			if i < 0 {
+				if _, ok := entry.(*files.FolderMetadata); !ok {
+					continue
+				}
+				i = len(rel)
+			}
+
+			sub, ok := byName[rel[:i]]
+			if !ok {
+				// Lowercasing can change the length of a path, so the
+				// display path is split by segment instead of offset
+				parts := strings.SplitN(pathDisplay, "/", depth+2)
+				sub = &FolderSize{
+					Name: parts[depth],
+					Path: c.unscopePath(strings.Join(parts[:depth+1], "/")),
+				}
+				byName[rel[:i]] = sub
+				subfolders = append(subfolders, sub)
+			}
+			if i < len(rel) {
+				sub.add(entry)
+			}
+		}
+
+		if !res.HasMore {
+			break
+		}
+		res, err = c.filesClient.ListFolderContinue(files.NewListFolderContinueArg(res.Cursor))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to continue listing: %w", err)
+		}
+	}
+
+	return total, subfolders, nil
+}
@@ -0,0 +1,172 @@
+package dropbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/paper"
+)
+
+// The Paper API only covers docs created before Paper docs became regular
+// .paper files. Dropbox marks it deprecated but still serves it for those
+// docs, so its calls are exempt from the deprecation check.
+
+// ErrPaperUnavailable is returned when the Paper API refuses access to the
+// account as a whole.
+var ErrPaperUnavailable = errors.New("paper docs cannot be accessed for this account: Paper is disabled, " +
+	"or the account uses the current version of Paper, which stores docs as .paper files; " +
+	"find those with dropbox_search and read them with dropbox_export")
+
+// maxPaperListLimit is the most doc IDs the Paper API returns per page.
+const maxPaperListLimit = 1000
+
+// PaperListOptions selects and orders the docs listed by ListPaperDocs. Empty
+// fields use the Dropbox defaults: docs the user accessed, most recently
+// accessed first.
+type PaperListOptions struct {
+	// FilterBy is "docs_accessed" or "docs_created"
+	FilterBy string
+	// SortBy is "accessed", "modified" or "created"
+	SortBy string
+	// SortOrder is "ascending" or "descending"
+	SortOrder string
+	// Limit caps the number of doc IDs returned. Zero means up to 1,000.
+	Limit int
+}
+
+// ListPaperDocs returns the IDs of the Paper docs the user can access, up to
+// opts.Limit of them.
+func (c *Client) ListPaperDocs(opts PaperListOptions) ([]string, error) {
+	if c.basePath != "" {
+		return nil, fmt.Errorf("paper docs cannot be accessed when a base path is set, as they are not stored in folders")
+	}
+
+	arg := paper.NewListPaperDocsArgs()
+	if opts.FilterBy != "" {
+		arg.FilterBy = &paper.ListPaperDocsFilterBy{Tagged: dropbox.Tagged{Tag: opts.FilterBy}}
+	}
+	if opts.SortBy != "" {
+		arg.SortBy = &paper.ListPaperDocsSortBy{Tagged: dropbox.Tagged{Tag: opts.SortBy}}
+	}
+	if opts.SortOrder != "" {
+		arg.SortOrder = &paper.ListPaperDocsSortOrder{Tagged: dropbox.Tagged{Tag: opts.SortOrder}}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = maxPaperListLimit
+	}
+	arg.Limit = int32(min(opts.Limit, maxPaperListLimit)) // #nosec G115 - bounded by maxPaperListLimit
+
+	res, err := c.paperClient.DocsList(arg) //nolint:staticcheck // see the note on the Paper API above
+	if err != nil {
+		return nil, paperError(err, "failed to list Paper docs")
+	}
+
+	docIDs := res.DocIds
+	for res.HasMore && len(docIDs) < opts.Limit && res.Cursor != nil {
+		next := paper.NewListPaperDocsContinueArgs(res.Cursor.Value)
+		res, err = c.paperClient.DocsListContinue(next) //nolint:staticcheck // see the note on the Paper API above
+		if err != nil {
+			return nil, paperError(err, "failed to continue listing Paper docs")
+		}
+		docIDs = append(docIDs, res.DocIds...)
+	}
+
+	if len(docIDs) > opts.Limit {
+		docIDs = docIDs[:opts.Limit]
+	}
+	return docIDs, nil
+}
+
+// CreatePaperDoc creates a Paper doc from content in format, which is
+// "markdown", "html" or "plain_text". The doc is created in the Paper folder
+// parentFolderID, or at the top level when it is empty.
+func (c *Client) CreatePaperDoc(content []byte, format, parentFolderID string) (*paper.PaperDocCreateUpdateResult, error) {
+	if c.basePath != "" {
+		return nil, fmt.Errorf("paper docs cannot be created when a base path is set, as they are not stored in folders")
+	}
+
+	arg := paper.NewPaperDocCreateArgs(&paper.ImportFormat{Tagged: dropbox.Tagged{Tag: format}})
+	arg.ParentFolderId = parentFolderID
+
+	res, err := c.paperClient.DocsCreate(arg, bytes.NewReader(content)) //nolint:staticcheck // see the note on the Paper API above
+	if err != nil {
+		var createErr paper.DocsCreateAPIError
+		if errors.As(err, &createErr) && createErr.EndpointError != nil {
+			switch createErr.EndpointError.Tag {
+			case paper.PaperDocCreateErrorContentMalformed:
+				return nil, fmt.Errorf("the content is not valid %s", format)
+			case paper.PaperDocCreateErrorFolderNotFound:
+				return nil, fmt.Errorf("paper folder %s not found", parentFolderID)
+			case paper.PaperDocCreateErrorDocLengthExceeded:
+				return nil, fmt.Errorf("the content is longer than a Paper doc allows")
+			case paper.PaperDocCreateErrorImageSizeExceeded:
+				return nil, fmt.Errorf("the content contains an image larger than a Paper doc allows")
+			case paper.PaperDocCreateErrorInsufficientPermissions:
+				if parentFolderID != "" {
+					return nil, fmt.Errorf("the current account cannot create docs in Paper folder %s", parentFolderID)
+				}
+			}
+		}
+		return nil, paperError(err, "failed to create Paper doc")
+	}
+	return res, nil
+}
+
+// DownloadPaperDoc exports the Paper doc docID as "markdown" or "html". Docs
+// larger than maxBytes are refused.
+func (c *Client) DownloadPaperDoc(docID, format string, maxBytes int64) (*paper.PaperDocExportResult, []byte, error) {
+	if c.basePath != "" {
+		return nil, nil, fmt.Errorf("paper docs cannot be accessed when a base path is set, as they are not stored in folders")
+	}
+
+	arg := paper.NewPaperDocExport(docID, &paper.ExportFormat{Tagged: dropbox.Tagged{Tag: format}})
+	res, content, err := c.paperClient.DocsDownload(arg) //nolint:staticcheck // see the note on the Paper API above
+	if err != nil {
+		var downloadErr paper.DocsDownloadAPIError
+		if errors.As(err, &downloadErr) && downloadErr.EndpointError != nil &&
+			downloadErr.EndpointError.Tag == paper.DocLookupErrorDocNotFound {
+			return nil, nil, fmt.Errorf("paper doc %s not found", docID)
+		}
+		return nil, nil, paperError(err, "failed to download Paper doc")
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, maxBytes+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, nil, fmt.Errorf("paper doc %s is larger than the %d byte download limit", docID, maxBytes)
+	}
+	return res, data, nil
+}
+
+// paperError maps an insufficient_permissions error, which the Paper API
+// returns when Paper is disabled or the account has moved to Paper as files,
+// to ErrPaperUnavailable, and wraps any other error with message.
+func paperError(err error, message string) error {
+	var summary string
+	var listErr paper.DocsListAPIError
+	var continueErr paper.DocsListContinueAPIError
+	var createErr paper.DocsCreateAPIError
+	var downloadErr paper.DocsDownloadAPIError
+	switch {
+	case errors.As(err, &listErr):
+		summary = listErr.ErrorSummary
+	case errors.As(err, &continueErr):
+		summary = continueErr.ErrorSummary
+	case errors.As(err, &createErr):
+		summary = createErr.ErrorSummary
+	case errors.As(err, &downloadErr):
+		summary = downloadErr.ErrorSummary
+	}
+
+	if strings.HasPrefix(summary, paper.PaperApiBaseErrorInsufficientPermissions) {
+		return fmt.Errorf("%w (%v)", ErrPaperUnavailable, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
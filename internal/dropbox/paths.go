@@ -0,0 +1,65 @@
+package dropbox
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathKind identifies which of the Dropbox path formats a path uses.
+// See https://www.dropbox.com/developers/documentation/http/documentation#path-formats
+type PathKind string
+
+const (
+	// PathKindDisplay is a slash-separated path such as "/Documents/a.txt".
+	// The root folder is the empty string.
+	PathKindDisplay PathKind = "path"
+	// PathKindID is a file or folder ID such as "id:a4ayc_80_OEAAAAAAAAAXw".
+	PathKindID PathKind = "id"
+	// PathKindNamespace is a path relative to a namespace such as
+	// "ns:123456/Documents/a.txt".
+	PathKindNamespace PathKind = "ns"
+	// PathKindRevision refers to a specific file revision such as
+	// "rev:a1c10ce0dd78".
+	PathKindRevision PathKind = "rev"
+)
+
+var namespacePathPattern = regexp.MustCompile(`^ns:[0-9]+(/.*)?$`)
+
+// ParsePath reports which format path uses, rejecting paths that match none
+// of them.
+func ParsePath(path string) (PathKind, error) {
+	switch {
+	case path == "" || strings.HasPrefix(path, "/"):
+		return PathKindDisplay, nil
+	case strings.HasPrefix(path, "id:"):
+		if len(path) == len("id:") {
+			return "", fmt.Errorf("invalid path %q: id: must be followed by a file ID", path)
+		}
+		return PathKindID, nil
+	case strings.HasPrefix(path, "ns:"):
+		if !namespacePathPattern.MatchString(path) {
+			return "", fmt.Errorf("invalid path %q: ns: must be followed by a numeric namespace ID and an optional /path", path)
+		}
+		return PathKindNamespace, nil
+	case strings.HasPrefix(path, "rev:"):
+		if len(path) == len("rev:") {
+			return "", fmt.Errorf("invalid path %q: rev: must be followed by a revision", path)
+		}
+		return PathKindRevision, nil
+	}
+	return "", fmt.Errorf("invalid path %q: must start with /, id:, ns: or rev:", path)
+}
+
+// ValidatePath returns an error if path is not in a Dropbox path format.
+func ValidatePath(path string) error {
+	_, err := ParsePath(path)
+	return err
+}
+
+// IsDisplayPath reports whether path is a plain display path rather than an
+// ID, namespace or revision reference.
+func IsDisplayPath(path string) bool {
+	kind, err := ParsePath(path)
+	return err == nil && kind == PathKindDisplay
+}
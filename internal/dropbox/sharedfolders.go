@@ -0,0 +1,154 @@
+package dropbox
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
+)
+
+// GetSharedFolderMetadata returns the name, path and policies of the shared
+// folder with the given ID.
+func (c *Client) GetSharedFolderMetadata(sharedFolderID string) (*sharing.SharedFolderMetadata, error) {
+	folder, err := c.sharingClient.GetFolderMetadata(sharing.NewGetMetadataArgs(sharedFolderID))
+	if err != nil {
+		var metadataErr sharing.GetFolderMetadataAPIError
+		if errors.As(err, &metadataErr) && metadataErr.EndpointError != nil {
+			if accessErr := sharedFolderAccessError(sharedFolderID, metadataErr.EndpointError); accessErr != nil {
+				return nil, accessErr
+			}
+		}
+		return nil, fmt.Errorf("failed to get shared folder metadata: %w", err)
+	}
+
+	if err := c.scopeSharedFolder(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// ListMountableFolders lists the shared folders the current account can
+// mount or unmount. Folders mounted outside the base path are left out.
+func (c *Client) ListMountableFolders() ([]*sharing.SharedFolderMetadata, error) {
+	result, err := c.sharingClient.ListMountableFolders(sharing.NewListFoldersArgs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mountable folders: %w", err)
+	}
+
+	folders := result.Entries
+	for result.Cursor != "" {
+		result, err = c.sharingClient.ListMountableFoldersContinue(sharing.NewListFoldersContinueArg(result.Cursor))
+		if err != nil {
+			return nil, fmt.Errorf("failed to continue listing mountable folders: %w", err)
+		}
+		folders = append(folders, result.Entries...)
+	}
+
+	scoped := folders[:0]
+	for _, folder := range folders {
+		if folder.PathLower != "" && c.scopeSharedFolder(folder) != nil {
+			continue
+		}
+		scoped = append(scoped, folder)
+	}
+	return scoped, nil
+}
+
+// MountFolder mounts a shared folder in the current account's Dropbox and
+// returns its metadata, including the path it was mounted at.
+func (c *Client) MountFolder(sharedFolderID string) (*sharing.SharedFolderMetadata, error) {
+	if c.basePath != "" {
+		return nil, fmt.Errorf("shared folders cannot be mounted when a base path is set, as they are mounted at the root")
+	}
+
+	folder, err := c.sharingClient.MountFolder(sharing.NewMountFolderArg(sharedFolderID))
+	if err != nil {
+		var mountErr sharing.MountFolderAPIError
+		if errors.As(err, &mountErr) && mountErr.EndpointError != nil {
+			if reason := mountFolderError(sharedFolderID, mountErr.EndpointError); reason != nil {
+				return nil, reason
+			}
+		}
+		return nil, fmt.Errorf("failed to mount shared folder: %w", err)
+	}
+	return folder, nil
+}
+
+// UnmountFolder removes a shared folder from the current account's Dropbox.
+// The account stays a member and can mount the folder again.
+func (c *Client) UnmountFolder(sharedFolderID string) error {
+	if c.basePath != "" {
+		return fmt.Errorf("shared folders cannot be unmounted when a base path is set")
+	}
+
+	err := c.sharingClient.UnmountFolder(sharing.NewUnmountFolderArg(sharedFolderID))
+	if err != nil {
+		var unmountErr sharing.UnmountFolderAPIError
+		if errors.As(err, &unmountErr) && unmountErr.EndpointError != nil {
+			switch unmountErr.EndpointError.Tag {
+			case sharing.UnmountFolderErrorAccessError:
+				if accessErr := sharedFolderAccessError(sharedFolderID, unmountErr.EndpointError.AccessError); accessErr != nil {
+					return accessErr
+				}
+			case sharing.UnmountFolderErrorNoPermission:
+				return fmt.Errorf("the current account is not allowed to unmount shared folder %s", sharedFolderID)
+			case sharing.UnmountFolderErrorNotUnmountable:
+				return fmt.Errorf("shared folder %s cannot be unmounted", sharedFolderID)
+			}
+		}
+		return fmt.Errorf("failed to unmount shared folder: %w", err)
+	}
+	return nil
+}
+
+// mountFolderError explains why a shared folder cannot be mounted, or
+// returns nil for failures that need no explanation.
+func mountFolderError(sharedFolderID string, err *sharing.MountFolderError) error {
+	switch err.Tag {
+	case sharing.MountFolderErrorAccessError:
+		return sharedFolderAccessError(sharedFolderID, err.AccessError)
+	case sharing.MountFolderErrorAlreadyMounted:
+		return fmt.Errorf("shared folder %s is already mounted", sharedFolderID)
+	case sharing.MountFolderErrorInsideSharedFolder:
+		return fmt.Errorf("shared folder %s cannot be mounted inside another shared folder", sharedFolderID)
+	case sharing.MountFolderErrorInsufficientQuota:
+		return fmt.Errorf("the current account does not have enough space to mount shared folder %s", sharedFolderID)
+	case sharing.MountFolderErrorNoPermission:
+		return fmt.Errorf("the current account is not allowed to mount shared folder %s", sharedFolderID)
+	case sharing.MountFolderErrorNotMountable:
+		return fmt.Errorf("shared folder %s cannot be mounted", sharedFolderID)
+	}
+	return nil
+}
+
+// scopeSharedFolder strips the base path from the path of a mounted shared
+// folder, failing for folders mounted outside the base path.
+func (c *Client) scopeSharedFolder(folder *sharing.SharedFolderMetadata) error {
+	if c.basePath == "" {
+		return nil
+	}
+	if folder.PathLower == "" || !c.inBasePath(folder.PathLower) {
+		return fmt.Errorf("shared folder %s is outside the base path", folder.SharedFolderId)
+	}
+	folder.PathLower = c.unscopePath(folder.PathLower)
+	return nil
+}
+
+// sharedFolderAccessError explains why a shared folder cannot be accessed,
+// or returns nil for failures that need no explanation.
+func sharedFolderAccessError(sharedFolderID string, err *sharing.SharedFolderAccessError) error {
+	if err == nil {
+		return nil
+	}
+	switch err.Tag {
+	case sharing.SharedFolderAccessErrorInvalidId:
+		return fmt.Errorf("%s is not a shared folder ID", sharedFolderID)
+	case sharing.SharedFolderAccessErrorNotAMember:
+		return fmt.Errorf("the current account is not a member of shared folder %s", sharedFolderID)
+	case sharing.SharedFolderAccessErrorEmailUnverified:
+		return fmt.Errorf("shared folder %s can only be accessed once the account's email address is verified", sharedFolderID)
+	case sharing.SharedFolderAccessErrorUnmounted:
+		return fmt.Errorf("shared folder %s is not mounted; mount it to access its contents", sharedFolderID)
+	}
+	return nil
+}
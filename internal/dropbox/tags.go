@@ -0,0 +1,51 @@
+package dropbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// MaxTagsPaths is how many paths GetTags looks up in a single request.
+const MaxTagsPaths = 100
+
+// GetTags returns the texts of the tags of the items at paths, keyed by the
+// paths as given. Items without tags are left out. At most MaxTagsPaths
+// paths can be looked up at once.
+func (c *Client) GetTags(paths []string) (map[string][]string, error) {
+	if len(paths) > MaxTagsPaths {
+		return nil, fmt.Errorf("at most %d paths can be looked up at once, got %d", MaxTagsPaths, len(paths))
+	}
+
+	scoped := make([]string, len(paths))
+	// Dropbox may return paths in another case than they were requested in
+	byScoped := make(map[string]string, len(paths))
+	for i, path := range paths {
+		scopedPath, err := c.scopePath(path)
+		if err != nil {
+			return nil, err
+		}
+		scoped[i] = scopedPath
+		byScoped[strings.ToLower(scopedPath)] = path
+	}
+
+	result, err := c.filesClient.TagsGet(files.NewGetTagsArg(scoped))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	tags := make(map[string][]string, len(result.PathsToTags))
+	for _, entry := range result.PathsToTags {
+		path, ok := byScoped[strings.ToLower(entry.Path)]
+		if !ok {
+			continue
+		}
+		for _, tag := range entry.Tags {
+			if tag.UserGeneratedTag != nil {
+				tags[path] = append(tags[path], tag.UserGeneratedTag.TagText)
+			}
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,254 @@
+package dropbox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.ngs.io/dropbox-mcp-server/internal/config"
+)
+
+const notifyHost = "notify.dropboxapi.com"
+
+// authTransport adds the access token to each request. When Dropbox rejects
+// the token with 401, for example because it was revoked or the local clock
+// is off, it refreshes the token once and retries the request.
+type authTransport struct {
+	cfg  *config.Config
+	base http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Routes on the notify host, such as longpoll, take no authorization
+	if req.URL.Host == notifyHost {
+		return t.base.RoundTrip(req)
+	}
+
+	getBody, err := replayableBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	token := t.currentToken()
+	resp, err := t.base.RoundTrip(authorize(req, token, getBody))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || getBody == nil {
+		return resp, err
+	}
+
+	refreshMu.Lock()
+	// Another request may already have refreshed the token
+	if t.cfg.Token() == token {
+		if t.cfg.RefreshToken == "" || t.cfg.HasEnvAccessToken() {
+			refreshMu.Unlock()
+			return resp, nil
+		}
+		if err := refreshToken(t.cfg); err != nil {
+			refreshMu.Unlock()
+			resp.Body.Close()
+			return nil, fmt.Errorf("access token was rejected and could not be refreshed, please run dropbox_auth to re-authenticate: %w", err)
+		}
+	}
+	token = t.cfg.Token()
+	refreshMu.Unlock()
+
+	resp.Body.Close()
+	return t.base.RoundTrip(authorize(req, token, getBody))
+}
+
+func (t *authTransport) currentToken() string {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	return t.cfg.Token()
+}
+
+// authorize returns a copy of req carrying token, with a fresh body from
+// getBody when the request has one.
+func authorize(req *http.Request, token string, getBody func() (io.ReadCloser, error)) *http.Request {
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+token)
+	if getBody != nil && req.Body != nil {
+		if body, err := getBody(); err == nil {
+			authorized.Body = body
+		}
+	}
+	return authorized
+}
+
+// replayableBody returns a function that produces the request body again for
+// a retry, or nil when the body cannot be replayed. Requests without a body
+// can always be retried. RPC bodies are small JSON documents and are buffered;
+// streamed upload content is not.
+func replayableBody(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return http.NoBody, nil }, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}, nil
+}
+
+// userAgentTransport sets the User-Agent header, which the SDK leaves to the
+// Go default.
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// Defaults for the transport shared by all clients.
+const (
+	defaultMaxIdleConnsPerHost   = 10
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultDialTimeout           = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 60 * time.Second
+)
+
+var (
+	transportsMu sync.Mutex
+	// transports holds the transports per proxy URL, with "" for the proxy
+	// from the environment
+	transports = map[string]*breakerTransport{}
+)
+
+// transportFor returns the transport used for Dropbox API requests with the
+// proxy of cfg. A client is created for every tool call, so transports are
+// shared to keep connections pooled and failures counted across calls. Their
+// limits can be tuned with environment variables.
+func transportFor(cfg *config.Config) (http.RoundTripper, error) {
+	proxyURL, err := config.ParseProxyURL(cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	transport, ok := transports[cfg.ProxyURL]
+	if !ok {
+		base := newTransport(proxyURL)
+		// A longpoll only responds once something changes or it times
+		// out, which takes minutes
+		longpoll := base.Clone()
+		longpoll.ResponseHeaderTimeout = 0
+
+		transport = &breakerTransport{
+			breaker: newCircuitBreaker(),
+			base:    &proxyErrorTransport{base: base, longpoll: longpoll},
+		}
+		transports[cfg.ProxyURL] = transport
+	}
+	return transport, nil
+}
+
+// newTransport returns a transport that connects through proxyURL, or
+// through the proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY when it is nil.
+func newTransport(proxyURL *url.URL) *http.Transport {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != nil {
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   EnvDuration("DROPBOX_MCP_DIAL_TIMEOUT", defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   envInt("DROPBOX_MCP_MAX_IDLE_CONNS_PER_HOST", defaultMaxIdleConnsPerHost),
+		IdleConnTimeout:       EnvDuration("DROPBOX_MCP_IDLE_CONN_TIMEOUT", defaultIdleConnTimeout),
+		TLSHandshakeTimeout:   EnvDuration("DROPBOX_MCP_TLS_HANDSHAKE_TIMEOUT", defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: EnvDuration("DROPBOX_MCP_RESPONSE_HEADER_TIMEOUT", defaultResponseHeaderTimeout),
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// proxyErrorTransport sends requests to the notify host through the longpoll
+// transport and everything else through base. It names the proxy in
+// connection errors, which otherwise look like Dropbox itself could not be
+// reached.
+type proxyErrorTransport struct {
+	base     *http.Transport
+	longpoll *http.Transport
+}
+
+func (t *proxyErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.base
+	if req.URL.Host == notifyHost {
+		transport = t.longpoll
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	proxyURL, proxyErr := transport.Proxy(req)
+	if proxyErr != nil {
+		return nil, fmt.Errorf("invalid proxy configuration: %w", proxyErr)
+	}
+	if proxyURL == nil {
+		return resp, err
+	}
+	return nil, fmt.Errorf("failed to reach Dropbox through proxy %s: %w", proxyURL.Redacted(), err)
+}
+
+// EnvDuration parses a duration such as "30s" or "5m" from the named
+// environment variable. Zero disables a timeout; unset or invalid values
+// fall back to def.
+func EnvDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid %s %q, using %s\n", name, value, def)
+		return def
+	}
+	return d
+}
+
+// envInt parses a positive number from the named environment variable,
+// falling back to def when unset or invalid.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid %s %q, using %d\n", name, value, def)
+		return def
+	}
+	return n
+}
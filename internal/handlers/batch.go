@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// HandleCheckJob reports the status of an asynchronous batch job, so that
+// clients can start a batch without blocking and poll it later.
+func (h *Handler) HandleCheckJob(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		AsyncJobID string `json:"async_job_id"`
+		JobType    string `json:"job_type"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.AsyncJobID == "" || args.JobType == "" {
+		return nil, fmt.Errorf("async_job_id and job_type parameters are required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := client.CheckBatchJob(args.JobType, args.AsyncJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return batchJobResult(args.JobType, args.AsyncJobID, status), nil
+}
+
+// batchPollInterval is how often a synchronous batch checks on its job.
+const batchPollInterval = time.Second
+
+type relocationBatchArgs struct {
+	Entries []struct {
+		FromPath string `json:"from_path"`
+		ToPath   string `json:"to_path"`
+	} `json:"entries"`
+	Autorename bool `json:"autorename"`
+	Async      bool `json:"async"`
+}
+
+func (a *relocationBatchArgs) relocationEntries() ([]dropbox.RelocationEntry, error) {
+	if len(a.Entries) == 0 {
+		return nil, fmt.Errorf("entries parameter is required")
+	}
+
+	entries := make([]dropbox.RelocationEntry, 0, len(a.Entries))
+	for i, entry := range a.Entries {
+		if entry.FromPath == "" || entry.ToPath == "" {
+			return nil, fmt.Errorf("entries[%d]: from_path and to_path are required", i)
+		}
+		entries = append(entries, dropbox.RelocationEntry{FromPath: entry.FromPath, ToPath: entry.ToPath})
+	}
+	return entries, nil
+}
+
+//nolint:dupl // HandleMoveBatch and HandleCopyBatch are similar by design
+func (h *Handler) HandleMoveBatch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		relocationBatchArgs
+		AllowSharedFolder bool `json:"allow_shared_folder"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	entries, err := args.relocationEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !args.AllowSharedFolder {
+		if err := checkSharedFolders(client, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := client.MoveBatch(entries, args.Autorename)
+	if err != nil {
+		return nil, err
+	}
+
+	return finishBatch(ctx, client, dropbox.JobTypeMove, status, args.Async)
+}
+
+// defaultProgressChunkSize is how many entries dropbox_move_batch_with_progress
+// moves per batch job.
+const defaultProgressChunkSize = 10
+
+// HandleMoveBatchWithProgress moves entries in batch jobs of chunk_size
+// entries each and reports progress after every job, because a batch job
+// only reveals its per-entry results once all of them are done. If the call
+// runs out of time, the results so far are returned together with the
+// running job and the entries that were not started.
+func (h *Handler) HandleMoveBatchWithProgress(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		relocationBatchArgs
+		ChunkSize         int  `json:"chunk_size"`
+		AllowSharedFolder bool `json:"allow_shared_folder"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	entries, err := args.relocationEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := args.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultProgressChunkSize
+	}
+	if chunkSize < 0 {
+		return nil, fmt.Errorf("chunk_size must be positive")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !args.AllowSharedFolder {
+		if err := checkSharedFolders(client, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	total := len(entries)
+	items := make([]map[string]interface{}, 0, total)
+	failed := 0
+	result := map[string]interface{}{
+		"job_type": dropbox.JobTypeMove,
+		"total":    total,
+	}
+
+	for start := 0; start < total; start += chunkSize {
+		chunk := entries[start:min(start+chunkSize, total)]
+
+		status, err := client.MoveBatch(chunk, args.Autorename)
+		if err == nil {
+			status, err = pollBatch(ctx, client, dropbox.JobTypeMove, status)
+		}
+		if err != nil {
+			// Entries that were already moved stay moved, so they are
+			// reported along with the error
+			if start == 0 {
+				return nil, err
+			}
+			result["error"] = err.Error()
+			result["remaining"] = relocationItems(entries[start:])
+			break
+		}
+
+		if status.Status == dropbox.JobInProgress {
+			result["async_job_id"] = status.AsyncJobID
+			result["remaining"] = relocationItems(entries[start+len(chunk):])
+			result["message"] = fmt.Sprintf("Ran out of time: the next %d entries are still being moved, check on them with dropbox_check_job. "+
+				"The remaining entries were not started", len(chunk))
+			break
+		}
+
+		if status.Status == dropbox.JobFailed {
+			for range chunk {
+				items = append(items, map[string]interface{}{
+					"success": false,
+					"error":   status.Error,
+				})
+			}
+			failed += len(chunk)
+		} else {
+			chunkItems, chunkFailed := batchEntryItems(status.Entries)
+			items = append(items, chunkItems...)
+			failed += chunkFailed
+		}
+
+		reportProgress(ctx, len(items), total, fmt.Sprintf("%d/%d moved", len(items)-failed, total))
+	}
+
+	result["entries"] = items
+	result["failed"] = failed
+	result["completed"] = len(items)
+	switch {
+	case len(items) == total:
+		result["status"] = dropbox.JobComplete
+	case result["error"] != nil:
+		result["status"] = dropbox.JobFailed
+	default:
+		result["status"] = dropbox.JobInProgress
+	}
+	return result, nil
+}
+
+// relocationItems lists relocation entries in tool results.
+func relocationItems(entries []dropbox.RelocationEntry) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, map[string]interface{}{
+			"from_path": entry.FromPath,
+			"to_path":   entry.ToPath,
+		})
+	}
+	return items
+}
+
+//nolint:dupl // HandleMoveBatch and HandleCopyBatch are similar by design
+func (h *Handler) HandleCopyBatch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		relocationBatchArgs
+		Dedup bool `json:"dedup"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	entries, err := args.relocationEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := client.CopyBatch(entries, dropbox.CopyBatchOptions{
+		Autorename: args.Autorename,
+		Dedup:      args.Dedup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return finishBatch(ctx, client, dropbox.JobTypeCopy, status, args.Async)
+}
+
+func (h *Handler) HandleDeleteBatch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Paths []string `json:"paths"`
+		Async bool     `json:"async"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if len(args.Paths) == 0 {
+		return nil, fmt.Errorf("paths parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := client.DeleteBatch(args.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return finishBatch(ctx, client, dropbox.JobTypeDelete, status, args.Async)
+}
+
+// finishBatch returns the result of a started batch. Unless async is set, a
+// job still running in the background is polled until it completes. If the
+// call runs out of time first, the job ID is returned so the client can keep
+// polling with dropbox_check_job.
+func finishBatch(ctx context.Context, client *dropbox.Client, jobType string, status *dropbox.BatchJobStatus, async bool) (interface{}, error) {
+	asyncJobID := status.AsyncJobID
+
+	if !async {
+		var err error
+		status, err = pollBatch(ctx, client, jobType, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := batchJobResult(jobType, asyncJobID, status)
+	switch {
+	case status.Status != dropbox.JobInProgress:
+	case async:
+		result["message"] = "The job is running in the background, check on it with dropbox_check_job"
+	default:
+		result["message"] = "The job is still running, check on it with dropbox_check_job"
+	}
+	return result, nil
+}
+
+// pollBatch checks on a batch job until it is no longer in progress or ctx is
+// done, in which case the job is returned still in progress.
+func pollBatch(ctx context.Context, client *dropbox.Client, jobType string, status *dropbox.BatchJobStatus) (*dropbox.BatchJobStatus, error) {
+	asyncJobID := status.AsyncJobID
+	deduplicated := status.Deduplicated
+
+	for status.Status == dropbox.JobInProgress {
+		select {
+		case <-ctx.Done():
+			return status, nil
+		case <-time.After(batchPollInterval):
+		}
+
+		next, err := client.CheckBatchJob(jobType, asyncJobID)
+		if err != nil {
+			// A check cut short by the deadline is reported like a timeout above
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, err
+		}
+		next.AsyncJobID = asyncJobID
+		next.Deduplicated = deduplicated
+		status = next
+	}
+	return status, nil
+}
+
+// batchJobResult converts a batch job status into a tool result.
+func batchJobResult(jobType, asyncJobID string, status *dropbox.BatchJobStatus) map[string]interface{} {
+	result := map[string]interface{}{
+		"job_type": jobType,
+		"status":   status.Status,
+	}
+	if asyncJobID != "" {
+		result["async_job_id"] = asyncJobID
+	}
+	if status.Error != "" {
+		result["error"] = status.Error
+	}
+	if len(status.Deduplicated) > 0 {
+		result["deduplicated"] = dedupCopyItems(status.Deduplicated)
+	}
+	if status.Status != dropbox.JobComplete {
+		return result
+	}
+
+	entries, failed := batchEntryItems(status.Entries)
+	result["entries"] = entries
+	result["failed"] = failed
+	return result
+}
+
+// dedupCopyItems converts copies saved from a copy reference into result items.
+func dedupCopyItems(copies []dropbox.DedupCopy) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(copies))
+	for _, copied := range copies {
+		item := map[string]interface{}{
+			"from_path":      copied.FromPath,
+			"to_path":        copied.ToPath,
+			"reference_path": copied.ReferencePath,
+			"success":        copied.Error == "",
+		}
+		if copied.Error != "" {
+			item["error"] = copied.Error
+		} else if file, ok := copied.Metadata.(*files.FileMetadata); ok {
+			item["path"] = file.PathDisplay
+			item["size"] = file.Size
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// batchEntryItems converts the per-entry results of a batch job and counts
+// the failed entries.
+func batchEntryItems(results []dropbox.BatchEntryResult) ([]map[string]interface{}, int) {
+	entries := make([]map[string]interface{}, 0, len(results))
+	failed := 0
+	for _, entry := range results {
+		if entry.Error != "" {
+			failed++
+			entries = append(entries, map[string]interface{}{
+				"success": false,
+				"error":   entry.Error,
+			})
+			continue
+		}
+
+		item := listItems([]files.IsMetadata{entry.Metadata})[0]
+		item["success"] = true
+		entries = append(entries, item)
+	}
+	return entries, failed
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// Outcomes of dropbox_compare.
+const (
+	compareMatch         = "match"
+	compareDiffer        = "differ"
+	compareLocalMissing  = "local_missing"
+	compareRemoteMissing = "remote_missing"
+	compareBothMissing   = "both_missing"
+)
+
+// HandleCompare checks whether a local file has the same content as a
+// Dropbox file by comparing their content hashes. A missing file on either
+// side is reported in the result rather than as an error.
+func (h *Handler) HandleCompare(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		LocalPath string `json:"local_path"`
+		Path      string `json:"path"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.LocalPath == "" {
+		return nil, fmt.Errorf("local_path parameter is required")
+	}
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	localPath, err := filepath.Abs(args.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local_path: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"path":       args.Path,
+		"local_path": localPath,
+	}
+
+	remote, err := compareRemoteFile(client, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if remote != nil {
+		result["path"] = remote.PathDisplay
+		result["remote_hash"] = remote.ContentHash
+		result["remote_size"] = remote.Size
+	}
+
+	localHash, localSize, err := compareLocalFile(localPath)
+	if err != nil {
+		return nil, err
+	}
+	localExists := localHash != ""
+	if localExists {
+		result["local_hash"] = localHash
+		result["local_size"] = localSize
+	}
+
+	result["local_exists"] = localExists
+	result["remote_exists"] = remote != nil
+	result["match"] = localExists && remote != nil && localHash == remote.ContentHash
+
+	switch {
+	case !localExists && remote == nil:
+		result["status"] = compareBothMissing
+	case !localExists:
+		result["status"] = compareLocalMissing
+	case remote == nil:
+		result["status"] = compareRemoteMissing
+	case localHash == remote.ContentHash:
+		result["status"] = compareMatch
+	default:
+		result["status"] = compareDiffer
+	}
+
+	return result, nil
+}
+
+// compareRemoteFile returns the metadata of the Dropbox file at path, or nil
+// if nothing exists there.
+func compareRemoteFile(client *dropbox.Client, path string) (*files.FileMetadata, error) {
+	metadata, err := client.GetMetadata(path)
+	if dropbox.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file, ok := metadata.(*files.FileMetadata)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	return file, nil
+}
+
+// compareLocalFile returns the Dropbox content hash and size of the local
+// file at name, or an empty hash if the file does not exist.
+func compareLocalFile(name string) (string, int64, error) {
+	info, err := os.Stat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read local file: %w", err)
+	}
+	if info.IsDir() {
+		return "", 0, fmt.Errorf("%s is a directory", name)
+	}
+
+	hash, err := dropbox.FileContentHash(name)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash local file: %w", err)
+	}
+	return hash, info.Size(), nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// formatBytes formats a byte count for display, e.g. "1.4 MB".
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+type treeNode struct {
+	name     string
+	isDir    bool
+	size     uint64
+	children map[string]*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	if n.children == nil {
+		n.children = map[string]*treeNode{}
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &treeNode{name: name, isDir: true}
+		n.children[name] = c
+	}
+	return c
+}
+
+// formatTree renders entries below root as an indented tree, like the Unix
+// tree command.
+func formatTree(root string, entries []files.IsMetadata) string {
+	prefix := strings.ToLower(strings.TrimSuffix(root, "/")) + "/"
+	top := &treeNode{name: root, isDir: true}
+	if top.name == "" {
+		top.name = "/"
+	}
+
+	dirs, fileCount := 0, 0
+	for _, entry := range entries {
+		var pathLower, pathDisplay string
+		var isDir bool
+		var size uint64
+
+		switch e := entry.(type) {
+		case *files.FileMetadata:
+			pathLower, pathDisplay, size = e.PathLower, e.PathDisplay, e.Size
+			fileCount++
+		case *files.FolderMetadata:
+			pathLower, pathDisplay, isDir = e.PathLower, e.PathDisplay, true
+			dirs++
+		default:
+			continue
+		}
+
+		if !strings.HasPrefix(pathLower, prefix) {
+			continue
+		}
+
+		node := top
+		for _, part := range strings.Split(pathDisplay[len(prefix):], "/") {
+			node = node.child(part)
+		}
+		node.isDir = isDir
+		node.size = size
+	}
+
+	var b strings.Builder
+	b.WriteString(top.name)
+	b.WriteString("\n")
+	writeTreeChildren(&b, top, "")
+	fmt.Fprintf(&b, "\n%d directories, %d files\n", dirs, fileCount)
+	return b.String()
+}
+
+func writeTreeChildren(b *strings.Builder, node *treeNode, indent string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	for i, name := range names {
+		child := node.children[name]
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+
+		b.WriteString(indent + branch + child.name)
+		if !child.isDir {
+			fmt.Fprintf(b, " (%s)", formatBytes(child.size))
+		}
+		b.WriteString("\n")
+
+		if child.isDir {
+			writeTreeChildren(b, child, nextIndent)
+		}
+	}
+}
+
+// addHumanSizes adds a size_human field next to every numeric size field in
+// a handler result, leaving the raw byte count in place.
+func addHumanSizes(v interface{}) {
+	switch r := v.(type) {
+	case map[string]interface{}:
+		if size, ok := r["size"].(uint64); ok {
+			r["size_human"] = formatBytes(size)
+		}
+	case []map[string]interface{}:
+		for _, item := range r {
+			addHumanSizes(item)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
 	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
@@ -15,12 +24,28 @@ import (
 )
 
 const (
-	typeFile   = "file"
-	typeFolder = "folder"
+	typeFile    = "file"
+	typeFolder  = "folder"
+	typeNone    = "none"
+	typeDeleted = "deleted"
+
+	outputJSON = "json"
+	outputTree = "tree"
 )
 
+// ClientFactory creates the Dropbox client a handler call runs against.
+type ClientFactory func(ctx context.Context, cfg *config.Config) (*dropbox.Client, error)
+
 type Handler struct {
 	config *config.Config
+
+	// newClient is dropbox.NewClient outside of tests
+	newClient ClientFactory
+
+	// cursors holds the latest list-folder cursor per folder for
+	// incremental listings. It lives for the lifetime of the process.
+	cursorsMu sync.Mutex
+	cursors   map[string]string
 }
 
 func NewHandler() (*Handler, error) {
@@ -28,13 +53,78 @@ func NewHandler() (*Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Handler{config: cfg}, nil
+
+	// A misconfigured temp directory would otherwise only surface once a
+	// download is half done
+	if err := config.ValidateTempDir(cfg.TempDir); err != nil {
+		return nil, err
+	}
+
+	// Headless deployments supply a refresh token instead of running the
+	// interactive OAuth flow, so exchange it for an access token up front
+	if os.Getenv(config.EnvRefreshToken) != "" && !cfg.HasEnvAccessToken() {
+		if cfg.ClientID == "" || cfg.ClientSecret == "" {
+			return nil, fmt.Errorf("%s requires DROPBOX_CLIENT_ID and DROPBOX_CLIENT_SECRET", config.EnvRefreshToken)
+		}
+		if err := dropbox.RefreshToken(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewHandlerFromConfig(cfg), nil
+}
+
+// NewHandlerFromConfig returns a handler for cfg without exchanging a refresh
+// token, for uses such as listing tools that never call Dropbox.
+func NewHandlerFromConfig(cfg *config.Config) *Handler {
+	return &Handler{config: cfg, newClient: dropbox.NewClient, cursors: map[string]string{}}
+}
+
+// ToolEnabled reports whether the named tool is enabled by configuration.
+func (h *Handler) ToolEnabled(name string) bool {
+	if h.config.BasePath != "" && unconfinedTools[name] {
+		return false
+	}
+	return h.config.ToolEnabled(name)
+}
+
+// unconfinedTools switch the account or namespace that paths are resolved
+// in, which would let a caller step outside the base path. They are not
+// offered while a base path is set.
+var unconfinedTools = map[string]bool{
+	"dropbox_set_team_member": true,
+	"dropbox_set_path_root":   true,
+}
+
+// errBasePathSet is returned by tools that cannot be used with a base path.
+var errBasePathSet = errors.New("not available when a base path is set, as it would give access outside of it")
+
+// ScopeGranted reports whether the current token was granted scope. It is
+// true when the granted scopes are unknown.
+func (h *Handler) ScopeGranted(scope string) bool {
+	return h.config.HasScope(scope)
+}
+
+// Debug reports whether error results include the raw Dropbox API response
+// by default.
+func (h *Handler) Debug() bool {
+	return h.config.Debug
 }
 
-func (h *Handler) HandleAuth(params json.RawMessage) (interface{}, error) {
+// ConfiguredToolNames returns every tool name listed in the enabled and
+// disabled tool configuration.
+func (h *Handler) ConfiguredToolNames() []string {
+	names := make([]string, 0, len(h.config.EnabledTools)+len(h.config.DisabledTools))
+	names = append(names, h.config.EnabledTools...)
+	return append(names, h.config.DisabledTools...)
+}
+
+func (h *Handler) HandleAuth(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		ClientID     string `json:"client_id"`
 		ClientSecret string `json:"client_secret"`
+		RedirectURI  string `json:"redirect_uri"`
+		RedirectHost string `json:"redirect_host"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
@@ -52,9 +142,18 @@ func (h *Handler) HandleAuth(params json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("client_id and client_secret are required (provide as parameters or environment variables)")
 	}
 
+	if args.RedirectURI == "" {
+		args.RedirectURI = h.config.RedirectURI
+	}
+	if args.RedirectHost == "" {
+		args.RedirectHost = h.config.RedirectHost
+	}
+
 	authConfig := auth.OAuthConfig{
 		ClientID:     args.ClientID,
 		ClientSecret: args.ClientSecret,
+		RedirectURI:  args.RedirectURI,
+		RedirectHost: args.RedirectHost,
 	}
 
 	result, err := auth.StartOAuthFlow(authConfig)
@@ -62,9 +161,58 @@ func (h *Handler) HandleAuth(params json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	h.config.ClientID = args.ClientID
-	h.config.ClientSecret = args.ClientSecret
+	return h.saveAuth(authConfig, result)
+}
+
+// HandleExchangeCode completes authentication with an authorization code
+// that was obtained out of band, without a browser or local server.
+func (h *Handler) HandleExchangeCode(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Code         string `json:"code"`
+		RedirectURI  string `json:"redirect_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Code == "" {
+		return nil, fmt.Errorf("code parameter is required")
+	}
+	if args.ClientID == "" {
+		args.ClientID = os.Getenv("DROPBOX_CLIENT_ID")
+	}
+	if args.ClientSecret == "" {
+		args.ClientSecret = os.Getenv("DROPBOX_CLIENT_SECRET")
+	}
+
+	if args.ClientID == "" || args.ClientSecret == "" {
+		return nil, fmt.Errorf("client_id and client_secret are required (provide as parameters or environment variables)")
+	}
+
+	authConfig := auth.OAuthConfig{
+		ClientID:     args.ClientID,
+		ClientSecret: args.ClientSecret,
+	}
+
+	result, err := auth.ExchangeCode(authConfig, args.Code, args.RedirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return h.saveAuth(authConfig, result)
+}
+
+// saveAuth stores the credentials and tokens of a new authorization.
+func (h *Handler) saveAuth(authConfig auth.OAuthConfig, result *auth.AuthResult) (interface{}, error) {
+	h.config.ClientID = authConfig.ClientID
+	h.config.ClientSecret = authConfig.ClientSecret
 	h.config.UpdateTokens(result.AccessToken, result.RefreshToken, result.ExpiresAt)
+	// A new authorization replaces the grant, so forget the old scopes
+	h.config.Scopes = nil
+	h.config.UpdateScopes(result.Scopes)
 
 	if err := h.config.Save(); err != nil {
 		return nil, fmt.Errorf("failed to save configuration: %w", err)
@@ -76,7 +224,7 @@ func (h *Handler) HandleAuth(params json.RawMessage) (interface{}, error) {
 	}, nil
 }
 
-func (h *Handler) HandleCheckAuth(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleCheckAuth(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	if !h.config.IsTokenValid() {
 		return map[string]interface{}{
 			"authenticated": false,
@@ -84,55 +232,241 @@ func (h *Handler) HandleCheckAuth(params json.RawMessage) (interface{}, error) {
 		}, nil
 	}
 
-	if err := auth.ValidateToken(h.config.AccessToken); err != nil {
+	if err := dropbox.ValidateToken(h.config); err != nil {
 		return map[string]interface{}{
 			"authenticated": false,
 			"message":       "Token is invalid or expired. Please re-authenticate.",
 		}, nil
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"authenticated": true,
 		"message":       "Authenticated with Dropbox",
-		"expires_at":    h.config.ExpiresAt,
+		"scopes":        grantedScopes(h.config),
+	}
+	if h.config.HasEnvAccessToken() {
+		result["token_source"] = config.EnvAccessToken
+	} else {
+		result["expires_at"] = h.config.ExpiresAt
+	}
+	return result, nil
+}
+
+// scopesUnknown is reported when Dropbox did not say which scopes a token
+// has, as for pre-generated tokens and tokens saved by older versions.
+const scopesUnknown = "unknown"
+
+// grantedScopes returns the sorted scopes of the current token, or
+// scopesUnknown. Dropbox only reports scopes when it issues a token, so they
+// cannot be looked up for a token supplied from outside.
+func grantedScopes(cfg *config.Config) interface{} {
+	if cfg.HasEnvAccessToken() || len(cfg.Scopes) == 0 {
+		return scopesUnknown
+	}
+	scopes := append([]string(nil), cfg.Scopes...)
+	sort.Strings(scopes)
+	return scopes
+}
+
+func (h *Handler) HandlePing(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	result := map[string]interface{}{
+		"status":        "ok",
+		"authenticated": false,
+	}
+
+	if !h.config.IsTokenValid() {
+		return result, nil
+	}
+
+	if err := dropbox.ValidateToken(h.config); err != nil {
+		result["message"] = fmt.Sprintf("Token validation failed: %v", err)
+		return result, nil
+	}
+
+	result["authenticated"] = true
+	return result, nil
+}
+
+// HandleStatus combines authentication, account and space usage details. Each
+// part fails independently so that a partial outage still reports the rest.
+func (h *Handler) HandleStatus(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	result := map[string]interface{}{
+		"authenticated": false,
+	}
+	if !h.config.ExpiresAt.IsZero() {
+		result["expires_at"] = h.config.ExpiresAt
+	}
+
+	if !h.config.IsTokenValid() {
+		result["message"] = "Not authenticated. Please run dropbox_auth first."
+		return result, nil
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		result["message"] = err.Error()
+		return result, nil
+	}
+
+	failures := map[string]string{}
+
+	account, err := client.GetCurrentAccount()
+	if err != nil {
+		failures["account"] = err.Error()
+	} else {
+		// A successful API call proves the token works
+		result["authenticated"] = true
+		accountInfo := map[string]interface{}{
+			"account_id": account.AccountId,
+			"email":      account.Email,
+		}
+		if account.Name != nil {
+			accountInfo["name"] = account.Name.DisplayName
+		}
+		result["account"] = accountInfo
+	}
+
+	usage, err := client.GetSpaceUsage()
+	if err != nil {
+		failures["space"] = err.Error()
+	} else {
+		result["authenticated"] = true
+		space := map[string]interface{}{
+			"used": usage.Used,
+		}
+		if usage.Allocation != nil {
+			switch {
+			case usage.Allocation.Individual != nil:
+				space["allocated"] = usage.Allocation.Individual.Allocated
+			case usage.Allocation.Team != nil:
+				space["allocated"] = usage.Allocation.Team.Allocated
+				space["team_used"] = usage.Allocation.Team.Used
+			}
+		}
+		result["space"] = space
+	}
+
+	if len(failures) > 0 {
+		result["errors"] = failures
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleSetTeamMember(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemberID string `json:"member_id"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if h.config.BasePath != "" {
+		return nil, errBasePathSet
+	}
+
+	h.config.TeamMemberID = args.MemberID
+
+	if err := h.config.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	if args.MemberID == "" {
+		return map[string]interface{}{
+			"status":  "success",
+			"message": "Cleared team member; file operations run as the token owner",
+		}, nil
+	}
+
+	return map[string]interface{}{
+		"status":    "success",
+		"member_id": args.MemberID,
+		"message":   fmt.Sprintf("File operations now run as team member %s", args.MemberID),
 	}, nil
 }
 
-func (h *Handler) HandleList(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleSetPathRoot(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		PathRoot string `json:"path_root"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	if h.config.BasePath != "" {
+		return nil, errBasePathSet
+	}
+
+	if err := config.ValidatePathRoot(args.PathRoot); err != nil {
+		return nil, err
+	}
+
+	previous := h.config.PathRoot
+	h.config.PathRoot = args.PathRoot
+	h.config.SetRootNamespaceID("")
+
+	client, err := h.newClient(ctx, h.config)
+	if err == nil {
+		err = client.CheckPathRoot()
+	}
 	if err != nil {
+		h.config.PathRoot = previous
+		h.config.SetRootNamespaceID("")
 		return nil, err
 	}
 
-	entries, err := client.ListFolder(args.Path)
+	if err := h.config.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"status":    "success",
+		"path_root": args.PathRoot,
+	}
+	if args.PathRoot == config.PathRootRoot {
+		result["namespace_id"] = h.config.RootNamespaceID()
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleListTeamMembers(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		IncludeRemoved bool `json:"include_removed"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]map[string]interface{}, 0, len(entries))
-	for _, entry := range entries {
-		item := map[string]interface{}{}
+	members, err := client.ListTeamMembers(args.IncludeRemoved)
+	if err != nil {
+		return nil, err
+	}
 
-		switch e := entry.(type) {
-		case *files.FileMetadata:
-			item["name"] = e.Name
-			item["path"] = e.PathDisplay
-			item["type"] = typeFile
-			item["size"] = e.Size
-			item["modified"] = e.ServerModified
-			item["rev"] = e.Rev
-		case *files.FolderMetadata:
-			item["name"] = e.Name
-			item["path"] = e.PathDisplay
-			item["type"] = typeFolder
+	result := make([]map[string]interface{}, 0, len(members))
+	for _, member := range members {
+		profile := member.Profile
+		if profile == nil {
+			continue
+		}
+
+		item := map[string]interface{}{
+			"member_id": profile.TeamMemberId,
+			"email":     profile.Email,
+		}
+		if profile.Name != nil {
+			item["name"] = profile.Name.DisplayName
+		}
+		if profile.Status != nil {
+			item["status"] = profile.Status.Tag
 		}
 
 		result = append(result, item)
@@ -141,79 +475,672 @@ func (h *Handler) HandleList(params json.RawMessage) (interface{}, error) {
 	return result, nil
 }
 
-func (h *Handler) HandleSearch(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleList(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Query string `json:"query"`
-		Path  string `json:"path"`
+		Path        string `json:"path"`
+		Recursive   bool   `json:"recursive"`
+		Output      string `json:"output"`
+		Incremental bool   `json:"incremental"`
+		Summary     bool   `json:"summary"`
+		Limit       int    `json:"limit"`
+		Pattern     string `json:"pattern"`
+		IgnoreCase  bool   `json:"ignore_case"`
+		modifiedArgs
+		HumanSizes bool `json:"human_sizes"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	if args.Query == "" {
-		return nil, fmt.Errorf("query parameter is required")
+	filter, err := newEntryFilter(args.Pattern, args.IgnoreCase, args.ModifiedAfter, args.ModifiedBefore, args.IncludeFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateListOptions(args.Output, args.Incremental, args.Summary, args.Limit); err != nil {
+		return nil, err
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	matches, err := client.Search(args.Query, args.Path)
+	if args.Limit > 0 {
+		entries, cursor, err := client.ListFolderPage(args.Path, args.Recursive, uint32(args.Limit))
+		if err != nil {
+			return nil, err
+		}
+		result := h.listPageResult(filter.apply(entries), cursor, args.Summary, args.HumanSizes)
+		result["path"] = args.Path
+		return result, nil
+	}
+
+	if args.Incremental {
+		result, entries, err := h.listIncremental(client, args.Path, args.Recursive)
+		if err != nil {
+			return nil, err
+		}
+		entries = filter.apply(entries)
+		result["entries"] = listItems(entries)
+		if args.Summary {
+			result["summary"] = listSummary(entries)
+		}
+		if args.HumanSizes || h.config.HumanSizes {
+			addHumanSizes(result["entries"])
+			addHumanSizes(result["summary"])
+		}
+		return result, nil
+	}
+
+	var entries []files.IsMetadata
+	if args.Recursive {
+		entries, err = client.ListFolderRecursive(args.Path)
+	} else {
+		entries, err = client.ListFolder(args.Path)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]map[string]interface{}, 0, len(matches))
-	for _, match := range matches {
-		metadata := match.Metadata.Metadata
+	entries = filter.apply(entries)
+
+	if args.Output == outputTree {
+		return formatTree(args.Path, entries), nil
+	}
+
+	items := listItems(entries)
+
+	if !args.Summary {
+		if args.HumanSizes || h.config.HumanSizes {
+			addHumanSizes(items)
+		}
+		return items, nil
+	}
+
+	result := map[string]interface{}{
+		"path":    args.Path,
+		"entries": items,
+		"summary": listSummary(entries),
+	}
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(result["entries"])
+		addHumanSizes(result["summary"])
+	}
+
+	return result, nil
+}
+
+// listSummary counts the files and folders among entries and adds up the
+// size of the files.
+func listSummary(entries []files.IsMetadata) map[string]interface{} {
+	var fileCount, folderCount int
+	var size uint64
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case *files.FileMetadata:
+			fileCount++
+			size += e.Size
+		case *files.FolderMetadata:
+			folderCount++
+		}
+	}
+
+	return map[string]interface{}{
+		"files":   fileCount,
+		"folders": folderCount,
+		"size":    size,
+	}
+}
+
+// listItems converts folder listing entries into result items. Deleted
+// entries only appear in incremental listings.
+func listItems(entries []files.IsMetadata) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
 		item := map[string]interface{}{}
 
-		switch m := metadata.(type) {
+		switch e := entry.(type) {
 		case *files.FileMetadata:
-			item["name"] = m.Name
-			item["path"] = m.PathDisplay
+			item["name"] = e.Name
+			item["path"] = e.PathDisplay
 			item["type"] = typeFile
-			item["size"] = m.Size
-			item["modified"] = m.ServerModified
+			item["size"] = e.Size
+			item["modified"] = e.ServerModified
+			item["rev"] = e.Rev
+			addFileSharingInfo(item, e.SharingInfo)
 		case *files.FolderMetadata:
-			item["name"] = m.Name
-			item["path"] = m.PathDisplay
+			item["name"] = e.Name
+			item["path"] = e.PathDisplay
 			item["type"] = typeFolder
+			addFolderSharingInfo(item, e.SharingInfo)
+		case *files.DeletedMetadata:
+			item["name"] = e.Name
+			item["path"] = e.PathDisplay
+			item["type"] = typeDeleted
 		}
 
 		result = append(result, item)
 	}
+	return result
+}
 
-	return result, nil
+// addFileSharingInfo marks a file inside a shared folder. Nothing is added
+// when Dropbox returned no sharing info.
+func addFileSharingInfo(item map[string]interface{}, info *files.FileSharingInfo) {
+	if info == nil {
+		return
+	}
+	item["shared"] = true
+	item["read_only"] = info.ReadOnly
+	item["parent_shared_folder_id"] = info.ParentSharedFolderId
+}
+
+// addFolderSharingInfo marks a shared folder, or a folder inside one. Nothing
+// is added when Dropbox returned no sharing info.
+func addFolderSharingInfo(item map[string]interface{}, info *files.FolderSharingInfo) {
+	if info == nil {
+		return
+	}
+	item["shared"] = true
+	item["read_only"] = info.ReadOnly
+	if info.SharedFolderId != "" {
+		item["shared_folder_id"] = info.SharedFolderId
+	}
+	if info.ParentSharedFolderId != "" {
+		item["parent_shared_folder_id"] = info.ParentSharedFolderId
+	}
+}
+
+// maxListLimit is the largest page size Dropbox accepts for a listing.
+const maxListLimit = 2000
+
+// validateListOptions reports combinations of dropbox_list options that
+// cannot be used together.
+func validateListOptions(output string, incremental, summary bool, limit int) error {
+	if output != "" && output != outputJSON && output != outputTree {
+		return fmt.Errorf("output must be %q or %q", outputJSON, outputTree)
+	}
+	if incremental && output == outputTree {
+		return fmt.Errorf("incremental listings cannot use tree output")
+	}
+	if summary && output == outputTree {
+		return fmt.Errorf("tree output already ends with a summary")
+	}
+	if limit < 0 || limit > maxListLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+	}
+	if limit > 0 && (incremental || output == outputTree) {
+		return fmt.Errorf("limit cannot be combined with incremental or tree output")
+	}
+	return nil
 }
 
-func (h *Handler) HandleGetMetadata(params json.RawMessage) (interface{}, error) {
+// HandleListContinue returns the next page of a listing started by
+// dropbox_list with a limit. The cursor does not carry the filters of the
+// listing, so they are passed again.
+func (h *Handler) HandleListContinue(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		Cursor     string `json:"cursor"`
+		Summary    bool   `json:"summary"`
+		Pattern    string `json:"pattern"`
+		IgnoreCase bool   `json:"ignore_case"`
+		modifiedArgs
+		HumanSizes bool `json:"human_sizes"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	if args.Path == "" {
-		return nil, fmt.Errorf("path parameter is required")
+	if args.Cursor == "" {
+		return nil, fmt.Errorf("cursor parameter is required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	filter, err := newEntryFilter(args.Pattern, args.IgnoreCase, args.ModifiedAfter, args.ModifiedBefore, args.IncludeFolders)
 	if err != nil {
 		return nil, err
 	}
 
-	metadata, err := client.GetMetadata(args.Path)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	result := map[string]interface{}{}
-
+	entries, cursor, err := client.ListFolderContinuePage(args.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.listPageResult(filter.apply(entries), cursor, args.Summary, args.HumanSizes), nil
+}
+
+// listPageResult converts a page of a listing into a tool result. Like
+// search results, it carries next_cursor while has_more is true.
+func (h *Handler) listPageResult(entries []files.IsMetadata, cursor string, summary, humanSizes bool) map[string]interface{} {
+	result := map[string]interface{}{
+		"entries":  listItems(entries),
+		"has_more": cursor != "",
+	}
+	if cursor != "" {
+		result["next_cursor"] = cursor
+	}
+	if summary {
+		result["summary"] = listSummary(entries)
+	}
+	if humanSizes || h.config.HumanSizes {
+		addHumanSizes(result["entries"])
+		addHumanSizes(result["summary"])
+	}
+	return result
+}
+
+// listIncremental lists only what changed since the previous incremental
+// listing of the same folder. The first listing, and any listing whose cursor
+// has expired, returns the full contents with incremental set to false. The
+// listed entries are returned along with the result.
+func (h *Handler) listIncremental(client *dropbox.Client, path string, recursive bool) (map[string]interface{}, []files.IsMetadata, error) {
+	key := fmt.Sprintf("%s|%t|%s|%s|%s", strings.ToLower(path), recursive,
+		h.config.TeamMemberID, h.config.PathRoot, h.config.BasePath)
+
+	h.cursorsMu.Lock()
+	cursor := h.cursors[key]
+	h.cursorsMu.Unlock()
+
+	if cursor != "" {
+		entries, next, err := client.ListFolderChanges(cursor)
+		switch {
+		case err == nil:
+			h.setCursor(key, next)
+			return map[string]interface{}{
+				"path":        path,
+				"incremental": true,
+				"entries":     listItems(entries),
+			}, entries, nil
+		case !dropbox.IsCursorReset(err):
+			return nil, nil, err
+		}
+	}
+
+	entries, next, err := client.ListFolderWithCursor(path, recursive)
+	if err != nil {
+		return nil, nil, err
+	}
+	h.setCursor(key, next)
+
+	return map[string]interface{}{
+		"path":        path,
+		"incremental": false,
+		"entries":     listItems(entries),
+	}, entries, nil
+}
+
+func (h *Handler) setCursor(key, cursor string) {
+	h.cursorsMu.Lock()
+	defer h.cursorsMu.Unlock()
+	h.cursors[key] = cursor
+}
+
+func (h *Handler) HandleFolderSize(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path       string `json:"path"`
+		Subfolders bool   `json:"subfolders"`
+		HumanSizes bool   `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	total, subfolders, err := client.FolderSize(args.Path, args.Subfolders)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"path":    args.Path,
+		"files":   total.Files,
+		"folders": total.Folders,
+		"size":    total.Size,
+	}
+
+	if args.Subfolders {
+		// Largest first, which is what "what takes up the space" needs
+		sort.SliceStable(subfolders, func(i, j int) bool {
+			return subfolders[i].Size > subfolders[j].Size
+		})
+		items := make([]map[string]interface{}, 0, len(subfolders))
+		for _, sub := range subfolders {
+			items = append(items, map[string]interface{}{
+				"name":    sub.Name,
+				"path":    sub.Path,
+				"files":   sub.Files,
+				"folders": sub.Folders,
+				"size":    sub.Size,
+			})
+		}
+		result["subfolders"] = items
+	}
+
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(result)
+		addHumanSizes(result["subfolders"])
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleListDeleted(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path      string `json:"path"`
+		Recursive bool   `json:"recursive"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := client.ListDeleted(args.Path, args.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(deleted))
+	for _, entry := range deleted {
+		result = append(result, map[string]interface{}{
+			"name": entry.Name,
+			"path": entry.PathDisplay,
+			"type": typeDeleted,
+		})
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleGetLatestCursor(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path           string `json:"path"`
+		Recursive      bool   `json:"recursive"`
+		IncludeDeleted bool   `json:"include_deleted"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := client.GetLatestCursor(args.Path, args.Recursive, args.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"path":   args.Path,
+		"cursor": cursor,
+	}, nil
+}
+
+// errWatchCursorReset reports a dropbox_watch cursor that has expired.
+var errWatchCursorReset = fmt.Errorf("the cursor has expired; call dropbox_watch without a cursor to start watching again")
+
+// Bounds of the longpoll timeout accepted by Dropbox, in seconds.
+const (
+	minWatchTimeout = 30
+	maxWatchTimeout = 480
+)
+
+// HandleWatch waits for changes below a folder and returns the changed
+// entries together with a cursor for watching on. Without a cursor it starts
+// from the current state of the folder, so only later changes are reported.
+func (h *Handler) HandleWatch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path       string `json:"path"`
+		Recursive  bool   `json:"recursive"`
+		Cursor     string `json:"cursor"`
+		Timeout    uint64 `json:"timeout"`
+		HumanSizes bool   `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Timeout == 0 {
+		args.Timeout = minWatchTimeout
+	}
+	if args.Timeout < minWatchTimeout || args.Timeout > maxWatchTimeout {
+		return nil, fmt.Errorf("timeout must be between %d and %d seconds", minWatchTimeout, maxWatchTimeout)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := args.Cursor
+	if cursor == "" {
+		cursor, err = client.GetLatestCursor(args.Path, args.Recursive, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changed, backoff, err := client.Longpoll(cursor, args.Timeout)
+	if dropbox.IsCursorReset(err) {
+		return nil, errWatchCursorReset
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"path":    args.Path,
+		"changes": []map[string]interface{}{},
+		"cursor":  cursor,
+	}
+	if backoff > 0 {
+		result["backoff"] = backoff
+	}
+	if !changed {
+		result["timed_out"] = true
+		return result, nil
+	}
+
+	entries, next, err := client.ListFolderChanges(cursor)
+	if dropbox.IsCursorReset(err) {
+		return nil, errWatchCursorReset
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result["changes"] = listItems(entries)
+	result["cursor"] = next
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(result["changes"])
+	}
+	return result, nil
+}
+
+// modifiedArgs are the arguments of tools that filter by modification time.
+type modifiedArgs struct {
+	ModifiedAfter  string `json:"modified_after"`
+	ModifiedBefore string `json:"modified_before"`
+	IncludeFolders bool   `json:"include_folders"`
+}
+
+func (h *Handler) HandleSearch(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Query   string `json:"query"`
+		Path    string `json:"path"`
+		OrderBy string `json:"order_by"`
+		modifiedArgs
+		HumanSizes bool `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Query == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+	switch args.OrderBy {
+	case "", files.SearchOrderByRelevance, files.SearchOrderByLastModifiedTime:
+	default:
+		return nil, fmt.Errorf("order_by must be %q or %q", files.SearchOrderByRelevance, files.SearchOrderByLastModifiedTime)
+	}
+
+	filter, err := newEntryFilter("", false, args.ModifiedAfter, args.ModifiedBefore, args.IncludeFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, cursor, err := client.Search(args.Query, args.Path, args.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.searchResult(matches, cursor, filter, args.HumanSizes), nil
+}
+
+func (h *Handler) HandleSearchContinue(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Cursor string `json:"cursor"`
+		modifiedArgs
+		HumanSizes bool `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Cursor == "" {
+		return nil, fmt.Errorf("cursor parameter is required")
+	}
+
+	filter, err := newEntryFilter("", false, args.ModifiedAfter, args.ModifiedBefore, args.IncludeFolders)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, cursor, err := client.SearchContinue(args.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.searchResult(matches, cursor, filter, args.HumanSizes), nil
+}
+
+// searchResult converts a page of search matches into a result. The cursor
+// for the next page is included when there is one.
+func (h *Handler) searchResult(matches []*files.SearchMatchV2, cursor string, filter *entryFilter, humanSizes bool) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(matches))
+	for _, match := range matches {
+		// Matches of other resource types carry no file metadata
+		if match.Metadata == nil || match.Metadata.Metadata == nil || !filter.keep(match.Metadata.Metadata) {
+			continue
+		}
+
+		item := map[string]interface{}{}
+
+		switch m := match.Metadata.Metadata.(type) {
+		case *files.FileMetadata:
+			item["name"] = m.Name
+			item["path"] = m.PathDisplay
+			item["type"] = typeFile
+			item["size"] = m.Size
+			item["modified"] = m.ServerModified
+		case *files.FolderMetadata:
+			item["name"] = m.Name
+			item["path"] = m.PathDisplay
+			item["type"] = typeFolder
+		case *files.DeletedMetadata:
+			item["name"] = m.Name
+			item["path"] = m.PathDisplay
+			item["type"] = typeDeleted
+		default:
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	if humanSizes || h.config.HumanSizes {
+		addHumanSizes(items)
+	}
+
+	result := map[string]interface{}{
+		"matches":  items,
+		"has_more": cursor != "",
+	}
+	if cursor != "" {
+		result["next_cursor"] = cursor
+	}
+	return result
+}
+
+func (h *Handler) HandleGetMetadata(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path        string `json:"path"`
+		IncludeLink bool   `json:"include_link"`
+		Verbose     bool   `json:"verbose"`
+		HumanSizes  bool   `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata files.IsMetadata
+	if args.Verbose {
+		metadata, err = client.GetMetadataVerbose(args.Path)
+	} else {
+		metadata, err = client.GetMetadata(args.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	if args.Verbose {
+		addVerboseMetadata(result, metadata)
+	}
+
 	switch m := metadata.(type) {
 	case *files.FileMetadata:
 		result["name"] = m.Name
@@ -223,57 +1150,526 @@ func (h *Handler) HandleGetMetadata(params json.RawMessage) (interface{}, error)
 		result["modified"] = m.ServerModified
 		result["rev"] = m.Rev
 		result["content_hash"] = m.ContentHash
+		addFileSharingInfo(result, m.SharingInfo)
+		addFileLockInfo(result, m.FileLockInfo)
+		if mimeType := mimeTypeByName(m.Name); mimeType != "" {
+			result["mime_type"] = mimeType
+		}
+		if args.IncludeLink {
+			// A missing link shouldn't hide the metadata, so only warn
+			link, linkErr := client.GetTemporaryLink(m.PathDisplay)
+			if linkErr != nil {
+				result["warning"] = linkErr.Error()
+			} else {
+				result["link"] = link
+			}
+		}
 	case *files.FolderMetadata:
 		result["name"] = m.Name
 		result["path"] = m.PathDisplay
 		result["type"] = typeFolder
 		result["id"] = m.Id
+		addFolderSharingInfo(result, m.SharingInfo)
+	}
+
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(result)
+	}
+
+	return result, nil
+}
+
+// addFileLockInfo adds whether a file is locked and, if so, who holds the
+// lock and since when, so that a locked file is not edited by mistake.
+func addFileLockInfo(result map[string]interface{}, lock *files.FileLockMetadata) {
+	result["locked"] = lock != nil
+	if lock == nil {
+		return
+	}
+	result["is_lock_holder"] = lock.IsLockholder
+	if lock.LockholderAccountId != "" {
+		result["lock_holder_account_id"] = lock.LockholderAccountId
+	}
+	if lock.LockholderName != "" {
+		result["lock_holder_name"] = lock.LockholderName
+	}
+	if lock.Created != nil {
+		result["lock_created"] = lock.Created.UTC().Format(time.RFC3339)
+	}
+}
+
+// addVerboseMetadata adds the fields returned by verbose metadata requests.
+// Optional fields are only added when Dropbox returned them.
+func addVerboseMetadata(result map[string]interface{}, metadata files.IsMetadata) {
+	switch m := metadata.(type) {
+	case *files.FileMetadata:
+		result["id"] = m.Id
+		result["client_modified"] = m.ClientModified
+		result["is_downloadable"] = m.IsDownloadable
+		result["has_explicit_shared_members"] = m.HasExplicitSharedMembers
+		if m.MediaInfo != nil {
+			result["media_info"] = m.MediaInfo
+		}
+		if m.SymlinkInfo != nil {
+			result["symlink_info"] = m.SymlinkInfo
+		}
+		if m.SharingInfo != nil {
+			result["sharing_info"] = m.SharingInfo
+		}
+		if m.ExportInfo != nil {
+			result["export_info"] = m.ExportInfo
+		}
+		if len(m.PropertyGroups) > 0 {
+			result["property_groups"] = m.PropertyGroups
+		}
+	case *files.FolderMetadata:
+		if m.SharingInfo != nil {
+			result["sharing_info"] = m.SharingInfo
+		}
+		if len(m.PropertyGroups) > 0 {
+			result["property_groups"] = m.PropertyGroups
+		}
+	}
+}
+
+func (h *Handler) HandleExists(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := client.GetMetadata(args.Path)
+	if err != nil {
+		if dropbox.IsNotFound(err) {
+			return map[string]interface{}{
+				"path":   args.Path,
+				"exists": false,
+				"type":   typeNone,
+			}, nil
+		}
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"path":   args.Path,
+		"exists": true,
+	}
+	switch m := metadata.(type) {
+	case *files.FileMetadata:
+		result["path"] = m.PathDisplay
+		result["type"] = typeFile
+	case *files.FolderMetadata:
+		result["path"] = m.PathDisplay
+		result["type"] = typeFolder
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleResolvePath(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	kind, err := dropbox.ParsePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := client.GetMetadata(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"input": args.Path,
+		"kind":  kind,
+	}
+	switch m := metadata.(type) {
+	case *files.FileMetadata:
+		result["id"] = m.Id
+		result["name"] = m.Name
+		result["path"] = m.PathDisplay
+		result["path_lower"] = m.PathLower
+		result["type"] = typeFile
+	case *files.FolderMetadata:
+		result["id"] = m.Id
+		result["name"] = m.Name
+		result["path"] = m.PathDisplay
+		result["path_lower"] = m.PathLower
+		result["type"] = typeFolder
 	}
 
 	return result, nil
 }
 
-func (h *Handler) HandleDownload(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleCheckLocks(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Paths []string `json:"paths"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if len(args.Paths) == 0 {
+		return nil, fmt.Errorf("paths parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(args.Paths))
+	for _, path := range args.Paths {
+		item := map[string]interface{}{
+			"path": path,
+		}
+
+		metadata, err := client.GetMetadata(path)
+		switch {
+		case dropbox.IsNotFound(err):
+			item["exists"] = false
+		case err != nil:
+			item["error"] = err.Error()
+		default:
+			item["exists"] = true
+			item["locked"] = false
+
+			switch m := metadata.(type) {
+			case *files.FileMetadata:
+				item["type"] = typeFile
+				addFileLockInfo(item, m.FileLockInfo)
+			case *files.FolderMetadata:
+				item["type"] = typeFolder
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleDownload(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path      string `json:"path"`
+		LocalPath string `json:"local_path"`
+		MaxBytes  int64  `json:"max_bytes"`
+		Offset    int64  `json:"offset"`
+		Length    int64  `json:"length"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	if args.Offset < 0 || args.Length < 0 {
+		return nil, fmt.Errorf("offset and length must not be negative")
+	}
+	ranged := args.Offset > 0 || args.Length > 0
+	if ranged && args.LocalPath != "" {
+		return nil, fmt.Errorf("offset and length cannot be combined with local_path")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Saving to disk streams the content, so no size limit applies
+	if args.LocalPath != "" {
+		target, err := filepath.Abs(args.LocalPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local_path: %w", err)
+		}
+
+		metadata, err := downloadToFile(client, args.Path, target)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"path":       metadata.PathDisplay,
+			"local_path": target,
+			"size":       metadata.Size,
+		}, nil
+	}
+
+	limit := h.config.DownloadLimit()
+	if args.MaxBytes > 0 {
+		limit = args.MaxBytes
+	}
+
+	metadata, err := client.GetMetadata(args.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if file, ok := metadata.(*files.FileMetadata); ok && ranged {
+		return downloadPage(client, args.Path, file, args.Offset, args.Length, limit)
+	}
+	if file, ok := metadata.(*files.FileMetadata); ok && int64(file.Size) > limit {
+		return nil, fmt.Errorf("%s is %d bytes, which exceeds the %d byte download limit; "+
+			"pass local_path to save it to disk, or raise max_bytes to return it inline", file.PathDisplay, file.Size, limit)
+	}
+
+	data, err := client.Download(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := detectMimeType(args.Path, data)
+
+	if isTextContent(data) {
+		return map[string]interface{}{
+			"content":   string(data),
+			"type":      "text",
+			"mime_type": mimeType,
+		}, nil
+	}
+
+	return map[string]interface{}{
+		"content":   base64.StdEncoding.EncodeToString(data),
+		"type":      "base64",
+		"mime_type": mimeType,
+	}, nil
+}
+
+// downloadPage returns up to length bytes of file starting at offset, capped
+// at limit, so that large text files such as logs can be read page by page.
+// The result reports whether more content follows and where it starts.
+func downloadPage(client *dropbox.Client, path string, file *files.FileMetadata, offset, length, limit int64) (interface{}, error) {
+	if length == 0 || length > limit {
+		length = limit
+	}
+	data, err := readRange(client, path, file, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(file.Size) // #nosec G115 - file sizes fit in int64
+	end := offset + int64(len(data))
+	// Don't split a multi-byte character across pages
+	if end < size && isTextContent(data) {
+		data = trimPartialRune(data)
+		end = offset + int64(len(data))
+	}
+
+	mimeType := mimeTypeByName(file.Name)
+	if offset == 0 {
+		mimeType = detectMimeType(file.Name, data)
+	}
+
+	result := map[string]interface{}{
+		"path":      file.PathDisplay,
+		"mime_type": mimeType,
+		"offset":    offset,
+		"length":    len(data),
+		"size":      file.Size,
+		"truncated": end < size,
+	}
+	if end < size {
+		result["next_offset"] = end
+	}
+	if isTextContent(data) {
+		result["content"] = string(data)
+		result["type"] = "text"
+	} else {
+		result["content"] = base64.StdEncoding.EncodeToString(data)
+		result["type"] = "base64"
+	}
+	return result, nil
+}
+
+// readRange reads up to length bytes of file from offset, stopping at the end
+// of the file. Offset 0 of an empty file reads nothing rather than failing.
+func readRange(client *dropbox.Client, path string, file *files.FileMetadata, offset, length int64) ([]byte, error) {
+	size := int64(file.Size) // #nosec G115 - file sizes fit in int64
+	if offset > 0 && offset >= size {
+		return nil, fmt.Errorf("offset %d is beyond the end of %s (%d bytes)", offset, file.PathDisplay, size)
+	}
+
+	// Reading past the end returns the bytes up to the end
+	if offset+length > size {
+		length = size - offset
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+	return client.DownloadRange(path, offset, length)
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of data.
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		start := len(data) - i
+		if !utf8.RuneStart(data[start]) {
+			continue
+		}
+		if !utf8.FullRune(data[start:]) {
+			return data[:start]
+		}
+		break
+	}
+	return data
+}
+
+// HandleReadRange returns a byte range of a file as base64, so that part of a
+// large binary file, such as its header, can be inspected without
+// downloading all of it.
+func (h *Handler) HandleReadRange(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path   string `json:"path"`
+		Offset int64  `json:"offset"`
+		Length int64  `json:"length"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	if args.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if args.Length <= 0 {
+		return nil, fmt.Errorf("length must be positive")
+	}
+	if limit := h.config.DownloadLimit(); args.Length > limit {
+		return nil, fmt.Errorf("length %d exceeds the %d byte download limit", args.Length, limit)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := client.GetMetadata(args.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	file, ok := metadata.(*files.FileMetadata)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a file", args.Path)
+	}
+
+	data, err := readRange(client, args.Path, file, args.Offset, args.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(file.Size) // #nosec G115 - file sizes fit in int64
+	return map[string]interface{}{
+		"path":    file.PathDisplay,
+		"offset":  args.Offset,
+		"length":  len(data),
+		"size":    file.Size,
+		"eof":     args.Offset+int64(len(data)) >= size,
+		"content": base64.StdEncoding.EncodeToString(data),
+		"type":    "base64",
+	}, nil
+}
+
+// HandleDownloadSharedLink downloads a file through a shared link, which
+// need not be in the user's own Dropbox.
+func (h *Handler) HandleDownloadSharedLink(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		URL          string `json:"url"`
+		Path         string `json:"path"`
+		LinkPassword string `json:"link_password"`
+		MaxBytes     int64  `json:"max_bytes"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	if args.Path == "" {
-		return nil, fmt.Errorf("path parameter is required")
+	if args.URL == "" {
+		return nil, fmt.Errorf("url parameter is required")
+	}
+	if args.Path != "" && !strings.HasPrefix(args.Path, "/") {
+		return nil, fmt.Errorf("path must be relative to the shared folder and start with /")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := client.Download(args.Path)
+	limit := h.config.DownloadLimit()
+	if args.MaxBytes > 0 {
+		limit = args.MaxBytes
+	}
+
+	link, data, err := client.DownloadSharedLink(args.URL, args.Path, args.LinkPassword, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if isTextContent(data) {
-		return map[string]interface{}{
-			"content": string(data),
-			"type":    "text",
-		}, nil
+	name := args.Path
+	if file, ok := link.(*sharing.FileLinkMetadata); ok {
+		name = file.Name
 	}
+	mimeType := detectMimeType(name, data)
 
-	return map[string]interface{}{
-		"content": base64.StdEncoding.EncodeToString(data),
-		"type":    "base64",
-	}, nil
+	result := map[string]interface{}{
+		"name":      name,
+		"mime_type": mimeType,
+	}
+	if isTextContent(data) {
+		result["content"] = string(data)
+		result["type"] = "text"
+	} else {
+		result["content"] = base64.StdEncoding.EncodeToString(data)
+		result["type"] = "base64"
+	}
+	return result, nil
 }
 
-func (h *Handler) HandleUpload(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleUpload(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path    string `json:"path"`
-		Content string `json:"content"`
-		Mode    string `json:"mode"`
+		Path           string `json:"path"`
+		Content        string `json:"content"`
+		SourceURL      string `json:"source_url"`
+		MaxBytes       int64  `json:"max_bytes"`
+		Mode           string `json:"mode"`
+		Rev            string `json:"rev"`
+		ClientModified string `json:"client_modified"`
+		ContentType    string `json:"content_type"`
+		Autorename     *bool  `json:"autorename"`
+		CheckConflict  bool   `json:"check_conflict"`
+		HumanSizes     bool   `json:"human_sizes"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
@@ -283,36 +1679,160 @@ func (h *Handler) HandleUpload(params json.RawMessage) (interface{}, error) {
 	if args.Path == "" {
 		return nil, fmt.Errorf("path parameter is required")
 	}
-	if args.Content == "" {
-		return nil, fmt.Errorf("content parameter is required")
+	if (args.Content == "") == (args.SourceURL == "") {
+		return nil, fmt.Errorf("exactly one of the content and source_url parameters is required")
+	}
+	if args.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(args.ContentType); err != nil {
+			return nil, fmt.Errorf("invalid content_type: %w", err)
+		}
 	}
 
-	if args.Mode == "" {
+	switch args.Mode {
+	case "":
 		args.Mode = "add"
+	case "add", "overwrite", "update":
+	default:
+		return nil, fmt.Errorf("mode must be %q, %q or %q", "add", "overwrite", "update")
+	}
+	if args.Mode == "update" && args.Rev == "" {
+		return nil, fmt.Errorf("rev parameter is required for update mode")
+	}
+
+	autorename := h.config.AutorenameUploads()
+	if args.Autorename != nil {
+		autorename = *args.Autorename
+	}
+
+	opts := dropbox.UploadOptions{
+		Mode:           args.Mode,
+		Rev:            args.Rev,
+		FailOnConflict: !autorename,
+	}
+	if args.ClientModified != "" {
+		clientModified, err := time.Parse(time.RFC3339, args.ClientModified)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client_modified: %w", err)
+		}
+		opts.ClientModified = &clientModified
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	metadata, err := client.Upload(args.Path, args.Content, args.Mode)
+	// Checking first avoids sending the content only to have it rejected
+	if args.CheckConflict && args.Mode == "add" && !autorename {
+		if err := checkUploadConflict(client, args.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	// For content from a source_url, data only holds its start, which is
+	// enough to detect its type
+	var data []byte
+	var metadata *files.FileMetadata
+	if args.SourceURL != "" {
+		upload := urlUpload{url: args.SourceURL, maxBytes: args.MaxBytes, path: args.Path, opts: opts}
+		metadata, data, err = upload.run(ctx, client)
+	} else {
+		data = dropbox.DecodeContent(args.Content)
+		metadata, err = client.Upload(args.Path, data, opts)
+	}
 	if err != nil {
+		if dropbox.IsUploadConflict(err) {
+			if args.Mode == "update" {
+				return revConflict(args.Path, args.Rev), nil
+			}
+			return nil, uploadConflictError(args.Path)
+		}
 		return nil, err
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"name":     metadata.Name,
 		"path":     metadata.PathDisplay,
 		"size":     metadata.Size,
 		"modified": metadata.ServerModified,
 		"rev":      metadata.Rev,
-	}, nil
+	}
+
+	// Dropbox saves a conflicting upload under an autorenamed path
+	renamed := uploadRenamed(args.Path, metadata)
+	result["renamed"] = renamed
+	if renamed {
+		result["renamed_from"] = args.Path
+		if args.Mode == "update" {
+			result["conflict"] = true
+			result["expected_rev"] = args.Rev
+			result["message"] = fmt.Sprintf("%s has changed since revision %s; the upload was saved as %s instead",
+				args.Path, args.Rev, metadata.PathDisplay)
+		}
+	}
+
+	// Dropbox derives the type of a file from its extension, so the type
+	// is only reported back for the caller to confirm
+	result["mime_type"] = args.ContentType
+	if args.ContentType == "" {
+		result["mime_type"] = detectMimeType(metadata.Name, data)
+	}
+	if warning := uploadTypeWarning(metadata.Name, args.ContentType, data); warning != "" {
+		result["warning"] = warning
+	}
+
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(result)
+	}
+
+	return result, nil
+}
+
+// uploadRenamed reports whether an upload to requested was autorenamed. The
+// returned path can differ from requested by more than the renaming, as
+// requested may be an id: or ns: path, so only the names are compared; a bare
+// id: path is compared with the ID of the file instead.
+func uploadRenamed(requested string, metadata *files.FileMetadata) bool {
+	if strings.HasPrefix(requested, "id:") && !strings.Contains(requested, "/") {
+		return metadata.Id != requested
+	}
+	return !strings.EqualFold(metadata.Name, requested[strings.LastIndex(requested, "/")+1:])
+}
+
+// checkUploadConflict returns a conflict error if something exists at path.
+func checkUploadConflict(client *dropbox.Client, path string) error {
+	_, err := client.GetMetadata(path)
+	switch {
+	case err == nil:
+		return uploadConflictError(path)
+	case dropbox.IsNotFound(err):
+		return nil
+	}
+	return err
+}
+
+func uploadConflictError(path string) error {
+	return fmt.Errorf("conflict: %s already exists; upload with mode 'overwrite' or 'update' to replace it, "+
+		"or with autorename to save it under a new name", path)
+}
+
+// revConflict reports an update-mode upload that Dropbox rejected because
+// path no longer has revision rev.
+func revConflict(path, rev string) map[string]interface{} {
+	return map[string]interface{}{
+		"conflict":     true,
+		"path":         path,
+		"expected_rev": rev,
+		"message": fmt.Sprintf("%s has changed since revision %s; the upload was not saved. "+
+			"Download the current version to merge the changes, or upload with mode 'overwrite' to replace it", path, rev),
+	}
 }
 
-func (h *Handler) HandleCreateFolder(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleCreateFolder(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		Path      string `json:"path"`
+		ExistOK   bool   `json:"exist_ok"`
+		Recursive bool   `json:"recursive"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
@@ -323,28 +1843,126 @@ func (h *Handler) HandleCreateFolder(params json.RawMessage) (interface{}, error
 		return nil, fmt.Errorf("path parameter is required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
+	if args.Recursive {
+		folder, created, err := client.CreateFolderRecursive(args.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"name":    folder.Name,
+			"path":    folder.PathDisplay,
+			"id":      folder.Id,
+			"created": created,
+		}, nil
+	}
+
 	metadata, err := client.CreateFolder(args.Path)
 	if err != nil {
-		return nil, err
+		if !args.ExistOK || !dropbox.IsFolderConflict(err) {
+			return nil, err
+		}
+
+		existing, metaErr := client.GetMetadata(args.Path)
+		if metaErr != nil {
+			return nil, fmt.Errorf("failed to get existing folder: %w", metaErr)
+		}
+		folder, ok := existing.(*files.FolderMetadata)
+		if !ok {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"name":    folder.Name,
+			"path":    folder.PathDisplay,
+			"id":      folder.Id,
+			"created": false,
+		}, nil
 	}
 
 	return map[string]interface{}{
-		"name": metadata.Name,
-		"path": metadata.PathDisplay,
-		"id":   metadata.Id,
+		"name":    metadata.Name,
+		"path":    metadata.PathDisplay,
+		"id":      metadata.Id,
+		"created": true,
 	}, nil
 }
 
+// moveGuidance explains how to resolve the move failures that come up when
+// reorganizing content across namespaces, keyed by relocation error tag.
+var moveGuidance = map[string]string{
+	files.RelocationErrorCantTransferOwnership: "the destination belongs to another namespace, so the move would transfer " +
+		"ownership of the content; retry with allow_ownership_transfer set to true",
+	files.RelocationErrorCantMoveSharedFolder: "shared folders cannot be moved into another namespace; " +
+		"copy the contents instead, or move the folder within its current namespace",
+	files.RelocationErrorCantNestSharedFolder: "a shared folder cannot be moved into another shared folder",
+}
+
+// sharedFolderAt returns the ID of the shared folder at path, or "" when
+// path is not the root of a shared folder or does not exist.
+func sharedFolderAt(client *dropbox.Client, path string) (string, error) {
+	metadata, err := client.GetMetadata(path)
+	if dropbox.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if folder, ok := metadata.(*files.FolderMetadata); ok && folder.SharingInfo != nil {
+		return folder.SharingInfo.SharedFolderId, nil
+	}
+	return "", nil
+}
+
+// replaceDestination retries a move that failed with moveErr after deleting
+// the file at toPath. A folder at toPath is never deleted to make room.
+func replaceDestination(
+	client *dropbox.Client, moveErr error, fromPath, toPath string, allowOwnershipTransfer bool,
+) (files.IsMetadata, bool, error) {
+	if !dropbox.IsDestinationConflict(moveErr) {
+		return nil, false, moveErr
+	}
+	if !dropbox.IsDestinationFileConflict(moveErr) {
+		return nil, false, fmt.Errorf("%w; overwrite only replaces a file, and %s is a folder", moveErr, toPath)
+	}
+
+	if err := client.Delete(toPath); err != nil {
+		return nil, false, fmt.Errorf("failed to replace destination: %w", err)
+	}
+	metadata, err := client.Move(fromPath, toPath, allowOwnershipTransfer)
+	return metadata, true, err
+}
+
+// checkSharedFolders refuses to move any entry that is a shared folder,
+// since collaborators may rely on where it is.
+func checkSharedFolders(client *dropbox.Client, entries []dropbox.RelocationEntry) error {
+	for i, entry := range entries {
+		sharedFolderID, err := sharedFolderAt(client, entry.FromPath)
+		if err != nil {
+			return fmt.Errorf("entries[%d]: %w", i, err)
+		}
+		if sharedFolderID != "" {
+			return fmt.Errorf("entries[%d]: %s is a shared folder (shared_folder_id %s); moving it changes where it appears "+
+				"and can disrupt collaborators who rely on its location. Pass allow_shared_folder: true to move shared folders anyway",
+				i, entry.FromPath, sharedFolderID)
+		}
+	}
+	return nil
+}
+
 //nolint:dupl // HandleMove and HandleCopy are similar by design
-func (h *Handler) HandleMove(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleMove(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		FromPath string `json:"from_path"`
-		ToPath   string `json:"to_path"`
+		FromPath               string `json:"from_path"`
+		ToPath                 string `json:"to_path"`
+		Overwrite              bool   `json:"overwrite"`
+		AllowOwnershipTransfer bool   `json:"allow_ownership_transfer"`
+		AllowSharedFolder      bool   `json:"allow_shared_folder"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
@@ -355,17 +1973,132 @@ func (h *Handler) HandleMove(params json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("from_path and to_path parameters are required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	fromPath, err := displayPath(client, args.FromPath)
+	if err != nil {
+		return nil, err
+	}
+	caseOnly := strings.EqualFold(fromPath, args.ToPath)
+	// A case-only move needs the source as a path to be detected
+	source := args.FromPath
+	if caseOnly {
+		source = fromPath
+	}
+
+	sharedFolderID, err := sharedFolderAt(client, args.FromPath)
 	if err != nil {
 		return nil, err
 	}
+	if sharedFolderID != "" && !args.AllowSharedFolder {
+		return nil, fmt.Errorf("%s is a shared folder (shared_folder_id %s); moving it changes where it appears and can "+
+			"disrupt collaborators who rely on its location, and it cannot be moved into another shared folder. "+
+			"Pass allow_shared_folder: true to move it anyway", args.FromPath, sharedFolderID)
+	}
 
-	metadata, err := client.Move(args.FromPath, args.ToPath)
+	metadata, err := client.Move(source, args.ToPath, args.AllowOwnershipTransfer)
+	replaced := false
+	// A case-only rename conflicts with the source itself, which must be kept
+	if err != nil && args.Overwrite && !caseOnly {
+		metadata, replaced, err = replaceDestination(client, err, source, args.ToPath, args.AllowOwnershipTransfer)
+	}
 	if err != nil {
+		if guidance, ok := moveGuidance[dropbox.MoveErrorTag(err)]; ok {
+			return nil, fmt.Errorf("%w; %s", err, guidance)
+		}
 		return nil, err
 	}
 
 	result := map[string]interface{}{}
+	if args.Overwrite {
+		result["replaced"] = replaced
+	}
+	if sharedFolderID != "" {
+		result["shared_folder_id"] = sharedFolderID
+	}
+
+	switch m := metadata.(type) {
+	case *files.FileMetadata:
+		result["name"] = m.Name
+		result["path"] = m.PathDisplay
+		result["type"] = typeFile
+		result["size"] = m.Size
+		result["modified"] = m.ServerModified
+	case *files.FolderMetadata:
+		result["name"] = m.Name
+		result["path"] = m.PathDisplay
+		result["type"] = typeFolder
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleRename(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path              string `json:"path"`
+		NewName           string `json:"new_name"`
+		AllowSharedFolder bool   `json:"allow_shared_folder"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" || args.NewName == "" {
+		return nil, fmt.Errorf("path and new_name parameters are required")
+	}
+	if strings.Contains(args.NewName, "/") {
+		return nil, fmt.Errorf("new_name must be a name, not a path; use dropbox_move to move items between folders")
+	}
+	if args.NewName == "." || args.NewName == ".." {
+		return nil, fmt.Errorf("invalid new_name %q", args.NewName)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	fromPath, err := displayPath(client, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	fromPath = strings.TrimSuffix(fromPath, "/")
+	if fromPath == "" {
+		return nil, fmt.Errorf("the root folder cannot be renamed")
+	}
+	toPath := fromPath[:strings.LastIndex(fromPath, "/")+1] + args.NewName
+
+	sharedFolderID, err := sharedFolderAt(client, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if sharedFolderID != "" && !args.AllowSharedFolder {
+		return nil, fmt.Errorf("%s is a shared folder (shared_folder_id %s); renaming it can disrupt collaborators "+
+			"who rely on its name. Pass allow_shared_folder: true to rename it anyway", fromPath, sharedFolderID)
+	}
+
+	// A case-only rename is detected by comparing display paths, which an ID
+	// path would hide
+	source := args.Path
+	if strings.EqualFold(fromPath, toPath) {
+		source = fromPath
+	}
+
+	metadata, err := client.Move(source, toPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"renamed_from": fromPath,
+	}
+	if sharedFolderID != "" {
+		result["shared_folder_id"] = sharedFolderID
+	}
 
 	switch m := metadata.(type) {
 	case *files.FileMetadata:
@@ -383,8 +2116,29 @@ func (h *Handler) HandleMove(params json.RawMessage) (interface{}, error) {
 	return result, nil
 }
 
+// displayPath returns the display path of the item at p. The parent folder
+// of an ID or namespace path is only known from its metadata.
+func displayPath(client *dropbox.Client, p string) (string, error) {
+	kind, err := dropbox.ParsePath(p)
+	if err != nil || kind == dropbox.PathKindDisplay {
+		return p, err
+	}
+
+	metadata, err := client.GetMetadata(p)
+	if err != nil {
+		return "", err
+	}
+	switch m := metadata.(type) {
+	case *files.FileMetadata:
+		return m.PathDisplay, nil
+	case *files.FolderMetadata:
+		return m.PathDisplay, nil
+	}
+	return "", fmt.Errorf("%s is not a file or folder", p)
+}
+
 //nolint:dupl // HandleMove and HandleCopy are similar by design
-func (h *Handler) HandleCopy(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleCopy(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		FromPath string `json:"from_path"`
 		ToPath   string `json:"to_path"`
@@ -398,7 +2152,7 @@ func (h *Handler) HandleCopy(params json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("from_path and to_path parameters are required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
@@ -426,7 +2180,7 @@ func (h *Handler) HandleCopy(params json.RawMessage) (interface{}, error) {
 	return result, nil
 }
 
-func (h *Handler) HandleDelete(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleDelete(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		Path string `json:"path"`
 	}
@@ -439,7 +2193,7 @@ func (h *Handler) HandleDelete(params json.RawMessage) (interface{}, error) {
 		return nil, fmt.Errorf("path parameter is required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
@@ -454,7 +2208,7 @@ func (h *Handler) HandleDelete(params json.RawMessage) (interface{}, error) {
 	}, nil
 }
 
-func (h *Handler) HandleCreateSharedLink(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleCreateSharedLink(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		Path     string                 `json:"path"`
 		Settings map[string]interface{} `json:"settings"`
@@ -468,7 +2222,7 @@ func (h *Handler) HandleCreateSharedLink(params json.RawMessage) (interface{}, e
 		return nil, fmt.Errorf("path parameter is required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
@@ -484,21 +2238,22 @@ func (h *Handler) HandleCreateSharedLink(params json.RawMessage) (interface{}, e
 	}, nil
 }
 
-func (h *Handler) HandleListSharedLinks(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleListSharedLinks(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		Path       string `json:"path"`
+		DirectOnly bool   `json:"direct_only"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	links, err := client.ListSharedLinks(args.Path)
+	links, err := client.ListSharedLinks(args.Path, args.DirectOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -530,7 +2285,7 @@ func (h *Handler) HandleListSharedLinks(params json.RawMessage) (interface{}, er
 	return result, nil
 }
 
-func (h *Handler) HandleRevokeSharedLink(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleRevokeSharedLink(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		URL string `json:"url"`
 	}
@@ -543,7 +2298,7 @@ func (h *Handler) HandleRevokeSharedLink(params json.RawMessage) (interface{}, e
 		return nil, fmt.Errorf("url parameter is required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
@@ -558,9 +2313,15 @@ func (h *Handler) HandleRevokeSharedLink(params json.RawMessage) (interface{}, e
 	}, nil
 }
 
-func (h *Handler) HandleGetRevisions(params json.RawMessage) (interface{}, error) {
+// maxRevisionsLimit is the most revisions Dropbox lists in one call.
+const maxRevisionsLimit = 100
+
+func (h *Handler) HandleGetRevisions(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
-		Path string `json:"path"`
+		Path       string `json:"path"`
+		Mode       string `json:"mode"`
+		Limit      uint64 `json:"limit"`
+		HumanSizes bool   `json:"human_sizes"`
 	}
 
 	if err := json.Unmarshal(params, &args); err != nil {
@@ -570,30 +2331,112 @@ func (h *Handler) HandleGetRevisions(params json.RawMessage) (interface{}, error
 	if args.Path == "" {
 		return nil, fmt.Errorf("path parameter is required")
 	}
+	switch args.Mode {
+	case "", files.ListRevisionsModePath, files.ListRevisionsModeId:
+	default:
+		return nil, fmt.Errorf("mode must be %q or %q", files.ListRevisionsModePath, files.ListRevisionsModeId)
+	}
+	if args.Limit == 0 {
+		args.Limit = maxRevisionsLimit
+	}
+	if args.Limit > maxRevisionsLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxRevisionsLimit)
+	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
 
-	revisions, err := client.GetRevisions(args.Path)
+	revisions, err := client.GetRevisions(args.Path, args.Mode, args.Limit)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]map[string]interface{}, 0, len(revisions))
-	for _, rev := range revisions {
-		result = append(result, map[string]interface{}{
-			"rev":      rev.Rev,
-			"size":     rev.Size,
-			"modified": rev.ServerModified,
-		})
+	items := make([]map[string]interface{}, 0, len(revisions.Entries))
+	for _, rev := range revisions.Entries {
+		item := map[string]interface{}{
+			"rev":          rev.Rev,
+			"name":         rev.Name,
+			"size":         rev.Size,
+			"modified":     rev.ServerModified,
+			"content_hash": rev.ContentHash,
+		}
+		// Revisions listed by ID may have been stored under earlier paths
+		if args.Mode == files.ListRevisionsModeId {
+			item["path"] = rev.PathDisplay
+		}
+		items = append(items, item)
+	}
+
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(items)
+	}
+
+	// The newest revision predates the deletion of a deleted file, so it is
+	// the one to restore
+	result := map[string]interface{}{
+		"revisions":  items,
+		"is_deleted": revisions.IsDeleted,
+	}
+	if revisions.ServerDeleted != nil {
+		result["server_deleted"] = *revisions.ServerDeleted
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleDiffRevisions(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path string `json:"path"`
+		RevA string `json:"rev_a"`
+		RevB string `json:"rev_b"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" || args.RevA == "" || args.RevB == "" {
+		return nil, fmt.Errorf("path, rev_a and rev_b parameters are required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	dataA, err := client.DownloadRevision(args.Path, args.RevA)
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := client.DownloadRevision(args.Path, args.RevB)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"path":  args.Path,
+		"rev_a": args.RevA,
+		"rev_b": args.RevB,
+	}
+
+	if !isTextContent(dataA) || !isTextContent(dataB) {
+		result["identical"] = bytes.Equal(dataA, dataB)
+		if !bytes.Equal(dataA, dataB) {
+			result["message"] = "Binary files differ"
+		}
+		return result, nil
 	}
 
+	diff := unifiedDiff(args.Path+"@"+args.RevA, args.Path+"@"+args.RevB, string(dataA), string(dataB))
+	result["identical"] = diff == ""
+	result["diff"] = diff
+
 	return result, nil
 }
 
-func (h *Handler) HandleRestoreFile(params json.RawMessage) (interface{}, error) {
+func (h *Handler) HandleRestoreFile(ctx context.Context, params json.RawMessage) (interface{}, error) {
 	var args struct {
 		Path string `json:"path"`
 		Rev  string `json:"rev"`
@@ -607,7 +2450,7 @@ func (h *Handler) HandleRestoreFile(params json.RawMessage) (interface{}, error)
 		return nil, fmt.Errorf("path and rev parameters are required")
 	}
 
-	client, err := dropbox.NewClient(h.config)
+	client, err := h.newClient(ctx, h.config)
 	if err != nil {
 		return nil, err
 	}
@@ -626,6 +2469,70 @@ func (h *Handler) HandleRestoreFile(params json.RawMessage) (interface{}, error)
 	}, nil
 }
 
+func (h *Handler) HandleExport(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path   string `json:"path"`
+		Format string `json:"format"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := client.GetMetadata(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := metadata.(*files.FileMetadata)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a file", args.Path)
+	}
+
+	if file.IsDownloadable {
+		return map[string]interface{}{
+			"exported": false,
+			"path":     file.PathDisplay,
+			"message":  "This file can be downloaded directly. Use dropbox_download instead.",
+		}, nil
+	}
+
+	res, data, err := client.Export(args.Path, args.Format)
+	if errors.Is(err, dropbox.ErrNonExportable) {
+		return nil, fmt.Errorf("%s cannot be downloaded or exported", args.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"exported": true,
+		"path":     file.PathDisplay,
+		"name":     res.ExportMetadata.Name,
+		"size":     res.ExportMetadata.Size,
+		"content":  base64.StdEncoding.EncodeToString(data),
+		"type":     "base64",
+	}
+	if file.ExportInfo != nil {
+		result["format"] = file.ExportInfo.ExportAs
+		result["export_options"] = file.ExportInfo.ExportOptions
+	}
+	if args.Format != "" {
+		result["format"] = args.Format
+	}
+
+	return result, nil
+}
+
 func isTextContent(data []byte) bool {
 	if len(data) == 0 {
 		return true
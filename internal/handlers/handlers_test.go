@@ -0,0 +1,1374 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/config"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// fakeFiles implements the methods of files.Client that a test sets. Calling
+// any other method panics on the nil embedded interface.
+type fakeFiles struct {
+	files.Client
+
+	listFolder         func(arg *files.ListFolderArg) (*files.ListFolderResult, error)
+	listFolderContinue func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error)
+	getLatestCursor    func(arg *files.ListFolderArg) (*files.ListFolderGetLatestCursorResult, error)
+	longpoll           func(arg *files.ListFolderLongpollArg) (*files.ListFolderLongpollResult, error)
+	getMetadata        func(arg *files.GetMetadataArg) (files.IsMetadata, error)
+	upload             func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error)
+	moveV2             func(arg *files.RelocationArg) (*files.RelocationResult, error)
+	deleteV2           func(arg *files.DeleteArg) (*files.DeleteResult, error)
+	moveBatchV2        func(arg *files.MoveBatchArg) (*files.RelocationBatchV2Launch, error)
+	downloadZip        func(arg *files.DownloadZipArg) (*files.DownloadZipResult, io.ReadCloser, error)
+	listRevisions      func(arg *files.ListRevisionsArg) (*files.ListRevisionsResult, error)
+	tagsGet            func(arg *files.GetTagsArg) (*files.GetTagsResult, error)
+
+	searchV2         func(arg *files.SearchV2Arg) (*files.SearchV2Result, error)
+	searchContinueV2 func(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error)
+}
+
+func (f *fakeFiles) ListFolder(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+	return f.listFolder(arg)
+}
+
+func (f *fakeFiles) ListFolderContinue(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+	return f.listFolderContinue(arg)
+}
+
+func (f *fakeFiles) ListFolderGetLatestCursor(arg *files.ListFolderArg) (*files.ListFolderGetLatestCursorResult, error) {
+	return f.getLatestCursor(arg)
+}
+
+func (f *fakeFiles) ListFolderLongpoll(arg *files.ListFolderLongpollArg) (*files.ListFolderLongpollResult, error) {
+	return f.longpoll(arg)
+}
+
+func (f *fakeFiles) GetMetadata(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+	return f.getMetadata(arg)
+}
+
+func (f *fakeFiles) ListRevisions(arg *files.ListRevisionsArg) (*files.ListRevisionsResult, error) {
+	return f.listRevisions(arg)
+}
+
+func (f *fakeFiles) TagsGet(arg *files.GetTagsArg) (*files.GetTagsResult, error) {
+	return f.tagsGet(arg)
+}
+
+func (f *fakeFiles) Upload(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+	return f.upload(arg, content)
+}
+
+func (f *fakeFiles) MoveV2(arg *files.RelocationArg) (*files.RelocationResult, error) {
+	return f.moveV2(arg)
+}
+
+func (f *fakeFiles) DeleteV2(arg *files.DeleteArg) (*files.DeleteResult, error) {
+	return f.deleteV2(arg)
+}
+
+func (f *fakeFiles) MoveBatchV2(arg *files.MoveBatchArg) (*files.RelocationBatchV2Launch, error) {
+	return f.moveBatchV2(arg)
+}
+
+func (f *fakeFiles) DownloadZip(arg *files.DownloadZipArg) (*files.DownloadZipResult, io.ReadCloser, error) {
+	return f.downloadZip(arg)
+}
+
+func (f *fakeFiles) SearchV2(arg *files.SearchV2Arg) (*files.SearchV2Result, error) {
+	return f.searchV2(arg)
+}
+
+func (f *fakeFiles) SearchContinueV2(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error) {
+	return f.searchContinueV2(arg)
+}
+
+// newTestHandler returns a handler whose calls run against filesClient.
+func newTestHandler(filesClient files.Client) *Handler {
+	return &Handler{
+		config: &config.Config{},
+		newClient: func(ctx context.Context, cfg *config.Config) (*dropbox.Client, error) {
+			return dropbox.NewClientFromSDK(cfg, filesClient, nil), nil
+		},
+		cursors: map[string]string{},
+	}
+}
+
+func fileEntry(path string, size uint64) *files.FileMetadata {
+	return &files.FileMetadata{
+		Metadata: files.Metadata{
+			Name:        path[strings.LastIndex(path, "/")+1:],
+			PathDisplay: path,
+			PathLower:   strings.ToLower(path),
+		},
+		Size: size,
+		Rev:  "015f",
+	}
+}
+
+func folderEntry(path string) *files.FolderMetadata {
+	return &files.FolderMetadata{
+		Metadata: files.Metadata{
+			Name:        path[strings.LastIndex(path, "/")+1:],
+			PathDisplay: path,
+			PathLower:   strings.ToLower(path),
+		},
+	}
+}
+
+func TestHandleListShapesEntries(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			if arg.Path != "/Docs" {
+				t.Errorf("Path = %q, want /Docs", arg.Path)
+			}
+			return &files.ListFolderResult{Entries: []files.IsMetadata{
+				folderEntry("/Docs/Archive"),
+				fileEntry("/Docs/notes.txt", 2048),
+			}}, nil
+		},
+	})
+
+	result, err := h.HandleList(context.Background(), json.RawMessage(`{"path":"/Docs","human_sizes":true}`))
+	if err != nil {
+		t.Fatalf("HandleList() error = %v", err)
+	}
+
+	items, ok := result.([]map[string]interface{})
+	if !ok || len(items) != 2 {
+		t.Fatalf("HandleList() = %#v, want two items", result)
+	}
+	if items[0]["type"] != typeFolder || items[0]["path"] != "/Docs/Archive" {
+		t.Errorf("items[0] = %v, want the Archive folder", items[0])
+	}
+	want := map[string]interface{}{
+		"name":       "notes.txt",
+		"path":       "/Docs/notes.txt",
+		"type":       typeFile,
+		"size":       uint64(2048),
+		"size_human": "2.0 KB",
+		"rev":        "015f",
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(items[1][key], value) {
+			t.Errorf("items[1][%q] = %#v, want %#v", key, items[1][key], value)
+		}
+	}
+}
+
+func TestHandleListSummary(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			if !arg.Recursive {
+				t.Error("Recursive = false, want true")
+			}
+			return &files.ListFolderResult{Entries: []files.IsMetadata{
+				folderEntry("/Docs/Archive"),
+				fileEntry("/Docs/Archive/old.txt", 1000),
+				fileEntry("/Docs/notes.txt", 24),
+			}}, nil
+		},
+	})
+
+	result, err := h.HandleList(context.Background(), json.RawMessage(`{"path":"/Docs","recursive":true,"summary":true}`))
+	if err != nil {
+		t.Fatalf("HandleList() error = %v", err)
+	}
+
+	listing := result.(map[string]interface{})
+	if entries := listing["entries"].([]map[string]interface{}); len(entries) != 3 {
+		t.Errorf("got %d entries, want 3", len(entries))
+	}
+	want := map[string]interface{}{"files": 2, "folders": 1, "size": uint64(1024)}
+	if !reflect.DeepEqual(listing["summary"], want) {
+		t.Errorf("summary = %v, want %v", listing["summary"], want)
+	}
+}
+
+func TestHandleListRejectsInvalidOutput(t *testing.T) {
+	h := newTestHandler(nil)
+	h.newClient = func(ctx context.Context, cfg *config.Config) (*dropbox.Client, error) {
+		t.Fatal("client created for invalid arguments")
+		return nil, nil
+	}
+
+	for _, params := range []string{
+		`{"path":"/Docs","output":"xml"}`,
+		`{"path":"/Docs","output":"tree","incremental":true}`,
+		`{"path":"/Docs","output":"tree","summary":true}`,
+		`{"path":`,
+	} {
+		if _, err := h.HandleList(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("HandleList(%s) succeeded, want an error", params)
+		}
+	}
+}
+
+func TestHandleExists(t *testing.T) {
+	notFound := files.GetMetadataAPIError{
+		EndpointError: &files.GetMetadataError{
+			Path: &files.LookupError{Tagged: sdk.Tagged{Tag: files.LookupErrorNotFound}},
+		},
+	}
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			if arg.Path == "/docs/report.pdf" {
+				return fileEntry("/Docs/Report.pdf", 1), nil
+			}
+			return nil, notFound
+		},
+	})
+
+	tests := []struct {
+		path string
+		want map[string]interface{}
+	}{
+		{"/docs/report.pdf", map[string]interface{}{"path": "/Docs/Report.pdf", "exists": true, "type": typeFile}},
+		{"/missing", map[string]interface{}{"path": "/missing", "exists": false, "type": typeNone}},
+	}
+	for _, tt := range tests {
+		params, _ := json.Marshal(map[string]string{"path": tt.path})
+		result, err := h.HandleExists(context.Background(), params)
+		if err != nil {
+			t.Fatalf("HandleExists(%s) error = %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(result, tt.want) {
+			t.Errorf("HandleExists(%s) = %v, want %v", tt.path, result, tt.want)
+		}
+	}
+}
+
+func TestHandleCompare(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(local, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := dropbox.FileContentHash(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := files.GetMetadataAPIError{
+		EndpointError: &files.GetMetadataError{
+			Path: &files.LookupError{Tagged: sdk.Tagged{Tag: files.LookupErrorNotFound}},
+		},
+	}
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			file := fileEntry("/Docs/notes.txt", 5)
+			switch arg.Path {
+			case "/docs/notes.txt":
+				file.ContentHash = hash
+			case "/docs/old.txt":
+				file.ContentHash = "0000"
+			default:
+				return nil, notFound
+			}
+			return file, nil
+		},
+	})
+
+	tests := []struct {
+		local, path, status string
+	}{
+		{local, "/docs/notes.txt", compareMatch},
+		{local, "/docs/old.txt", compareDiffer},
+		{filepath.Join(dir, "missing.txt"), "/docs/notes.txt", compareLocalMissing},
+		{local, "/docs/missing.txt", compareRemoteMissing},
+		{filepath.Join(dir, "missing.txt"), "/docs/missing.txt", compareBothMissing},
+	}
+	for _, tt := range tests {
+		params, _ := json.Marshal(map[string]string{"local_path": tt.local, "path": tt.path})
+		result, err := h.HandleCompare(context.Background(), params)
+		if err != nil {
+			t.Fatalf("HandleCompare(%s, %s) error = %v", tt.local, tt.path, err)
+		}
+		item := result.(map[string]interface{})
+		if item["status"] != tt.status || item["match"] != (tt.status == compareMatch) {
+			t.Errorf("HandleCompare(%s, %s) = %v, want status %s", tt.local, tt.path, item, tt.status)
+		}
+	}
+
+	if _, err := h.HandleCompare(context.Background(),
+		json.RawMessage(`{"local_path":"`+dir+`","path":"/docs/notes.txt"}`)); err == nil {
+		t.Error("HandleCompare() of a local directory succeeded, want an error")
+	}
+}
+
+func TestHandleGetRevisionsMode(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		listRevisions: func(arg *files.ListRevisionsArg) (*files.ListRevisionsResult, error) {
+			if arg.Mode.Tag != files.ListRevisionsModeId || arg.Limit != 2 {
+				t.Errorf("Mode = %s, Limit = %d, want id and 2", arg.Mode.Tag, arg.Limit)
+			}
+			deleted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			return &files.ListRevisionsResult{
+				IsDeleted:     true,
+				ServerDeleted: &deleted,
+				Entries: []*files.FileMetadata{
+					fileEntry("/Docs/final.txt", 5),
+					fileEntry("/Docs/draft.txt", 3),
+				},
+			}, nil
+		},
+	})
+
+	result, err := h.HandleGetRevisions(context.Background(),
+		json.RawMessage(`{"path":"id:a4ayc_80_OEAAAAAAAAAXw","mode":"id","limit":2}`))
+	if err != nil {
+		t.Fatalf("HandleGetRevisions() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	revisions := item["revisions"].([]map[string]interface{})
+	if len(revisions) != 2 || revisions[1]["path"] != "/Docs/draft.txt" || revisions[1]["name"] != "draft.txt" {
+		t.Errorf("HandleGetRevisions() = %v, want the earlier path of each revision", revisions)
+	}
+	if item["is_deleted"] != true || item["server_deleted"] == nil {
+		t.Errorf("HandleGetRevisions() = %v, want the deletion surfaced", item)
+	}
+
+	for _, params := range []string{
+		`{"path":"/Docs/final.txt","mode":"name"}`,
+		`{"path":"/Docs/final.txt","limit":101}`,
+	} {
+		if _, err := h.HandleGetRevisions(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("HandleGetRevisions(%s) succeeded, want an error", params)
+		}
+	}
+}
+
+func TestHandleUploadUpdateMode(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+			if arg.Mode.Tag != files.WriteModeUpdate || arg.Mode.Update != "015f" {
+				t.Errorf("Mode = %+v, want update of 015f", arg.Mode)
+			}
+			// The file has changed, so Dropbox autorenames the upload or,
+			// without autorename, rejects it
+			if arg.Autorename {
+				return fileEntry("/notes (conflicted copy).txt", 5), nil
+			}
+			return nil, files.UploadAPIError{EndpointError: &files.UploadError{
+				Tagged: sdk.Tagged{Tag: files.UploadErrorPath},
+				Path: &files.UploadWriteFailed{Reason: &files.WriteError{
+					Tagged:   sdk.Tagged{Tag: files.WriteErrorConflict},
+					Conflict: &files.WriteConflictError{Tagged: sdk.Tagged{Tag: files.WriteConflictErrorFile}},
+				}},
+			}}
+		},
+	})
+
+	if _, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","mode":"update"}`)); err == nil {
+		t.Error("HandleUpload() without rev succeeded, want an error")
+	}
+	if _, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","mode":"overrite"}`)); err == nil {
+		t.Error("HandleUpload() with an unknown mode succeeded, want an error")
+	}
+
+	result, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","mode":"update","rev":"015f"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	if item["conflict"] != true || item["path"] != "/notes (conflicted copy).txt" {
+		t.Errorf("HandleUpload() = %v, want a conflict at the renamed path", item)
+	}
+
+	autorename := false
+	h.config.UploadAutorename = &autorename
+	result, err = h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","mode":"update","rev":"015f"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() without autorename error = %v", err)
+	}
+	item = result.(map[string]interface{})
+	if item["conflict"] != true || item["path"] != "/notes.txt" || item["expected_rev"] != "015f" {
+		t.Errorf("HandleUpload() without autorename = %v, want a conflict with the expected rev", item)
+	}
+}
+
+func TestGrantedScopes(t *testing.T) {
+	if got := grantedScopes(&config.Config{}); got != scopesUnknown {
+		t.Errorf("grantedScopes() without scopes = %v, want %s", got, scopesUnknown)
+	}
+
+	cfg := &config.Config{Scopes: []string{"files.metadata.read", "account_info.read"}}
+	want := []string{"account_info.read", "files.metadata.read"}
+	if got := grantedScopes(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("grantedScopes() = %v, want %v", got, want)
+	}
+}
+
+func TestHandlersRequirePath(t *testing.T) {
+	h := newTestHandler(nil)
+
+	for name, handle := range map[string]func(context.Context, json.RawMessage) (interface{}, error){
+		"HandleExists":      h.HandleExists,
+		"HandleDelete":      h.HandleDelete,
+		"HandleGetMetadata": h.HandleGetMetadata,
+	} {
+		_, err := handle(context.Background(), json.RawMessage(`{}`))
+		if err == nil || !strings.Contains(err.Error(), "path parameter is required") {
+			t.Errorf("%s() error = %v, want a missing path error", name, err)
+		}
+	}
+}
+
+func TestBasePathHidesUnconfinedTools(t *testing.T) {
+	h := newTestHandler(&fakeFiles{})
+	h.config.BasePath = "/App"
+
+	for name, handle := range map[string]func(context.Context, json.RawMessage) (interface{}, error){
+		"dropbox_set_team_member": h.HandleSetTeamMember,
+		"dropbox_set_path_root":   h.HandleSetPathRoot,
+	} {
+		if h.ToolEnabled(name) {
+			t.Errorf("ToolEnabled(%s) = true with a base path", name)
+		}
+		if _, err := handle(context.Background(), json.RawMessage(`{}`)); !errors.Is(err, errBasePathSet) {
+			t.Errorf("%s error = %v, want errBasePathSet", name, err)
+		}
+	}
+	if h.config.TeamMemberID != "" || h.config.PathRoot != "" {
+		t.Errorf("config changed to member %q, path root %q", h.config.TeamMemberID, h.config.PathRoot)
+	}
+}
+
+func TestHandleGetMetadataLock(t *testing.T) {
+	created := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			file := fileEntry(arg.Path, 5)
+			if arg.Path == "/locked.docx" {
+				file.FileLockInfo = &files.FileLockMetadata{
+					LockholderName:      "Alice",
+					LockholderAccountId: "dbid:AAH4f99T0taONIb-OurWxbNQ6ywGRopQngc",
+					Created:             &created,
+				}
+			}
+			return file, nil
+		},
+	})
+
+	result, err := h.HandleGetMetadata(context.Background(), json.RawMessage(`{"path":"/locked.docx"}`))
+	if err != nil {
+		t.Fatalf("HandleGetMetadata() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	if item["locked"] != true || item["lock_holder_account_id"] != "dbid:AAH4f99T0taONIb-OurWxbNQ6ywGRopQngc" ||
+		item["lock_created"] != "2024-06-01T09:30:00Z" || item["is_lock_holder"] != false {
+		t.Errorf("HandleGetMetadata() = %v, want the lock held by Alice", item)
+	}
+
+	result, err = h.HandleGetMetadata(context.Background(), json.RawMessage(`{"path":"/free.docx"}`))
+	if err != nil {
+		t.Fatalf("HandleGetMetadata() error = %v", err)
+	}
+	if item := result.(map[string]interface{}); item["locked"] != false || item["lock_created"] != nil {
+		t.Errorf("HandleGetMetadata() = %v, want an unlocked file", item)
+	}
+
+	// Verbose metadata does not repeat the lock in its raw form
+	verbose := map[string]interface{}{}
+	addVerboseMetadata(verbose, &files.FileMetadata{FileLockInfo: &files.FileLockMetadata{LockholderName: "Alice"}})
+	if verbose["file_lock_info"] != nil {
+		t.Errorf("addVerboseMetadata() = %v, want no file_lock_info", verbose)
+	}
+
+	// dropbox_check_locks reports locks with the same fields
+	result, err = h.HandleCheckLocks(context.Background(), json.RawMessage(`{"paths":["/locked.docx","/free.docx"]}`))
+	if err != nil {
+		t.Fatalf("HandleCheckLocks() error = %v", err)
+	}
+	items := result.([]map[string]interface{})
+	if items[0]["locked"] != true || items[0]["lock_holder_name"] != "Alice" ||
+		items[0]["lock_created"] != "2024-06-01T09:30:00Z" || items[0]["is_lock_holder"] != false {
+		t.Errorf("HandleCheckLocks() = %v, want the lock held by Alice", items[0])
+	}
+	if items[1]["locked"] != false || items[1]["lock_holder_name"] != nil {
+		t.Errorf("HandleCheckLocks() = %v, want an unlocked file", items[1])
+	}
+}
+
+func TestHandlerReturnsClientError(t *testing.T) {
+	h := newTestHandler(nil)
+	h.newClient = func(ctx context.Context, cfg *config.Config) (*dropbox.Client, error) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	_, err := h.HandleDelete(context.Background(), json.RawMessage(`{"path":"/old"}`))
+	if err == nil || err.Error() != "invalid or expired token" {
+		t.Errorf("HandleDelete() error = %v, want the client error", err)
+	}
+}
+
+func TestUploadTypeWarning(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		warn        bool
+	}{
+		{"notes.txt", "", []byte("hello"), false},
+		{"image.png", "", png, false},
+		{"image.txt", "", png, true},
+		{"data.txt", "", []byte{0x00, 0x01, 0xfe}, true},
+		{"README", "", png, false},
+		{"report.pdf", "application/pdf", nil, false},
+		{"report.pdf", "text/html", nil, true},
+	}
+	for _, tt := range tests {
+		got := uploadTypeWarning(tt.name, tt.contentType, tt.data)
+		if (got != "") != tt.warn {
+			t.Errorf("uploadTypeWarning(%q, %q) = %q, want warning %t", tt.name, tt.contentType, got, tt.warn)
+		}
+	}
+}
+
+func TestHandleUploadConflicts(t *testing.T) {
+	existing := fileEntry("/notes.txt", 5)
+	var uploads int
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return existing, nil
+		},
+		upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+			uploads++
+			if !arg.Autorename {
+				t.Error("Autorename = false, want true")
+			}
+			return fileEntry("/notes (1).txt", 5), nil
+		},
+	})
+
+	_, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","autorename":false,"check_conflict":true}`))
+	if err == nil || !strings.Contains(err.Error(), "conflict: /notes.txt already exists") {
+		t.Errorf("HandleUpload() error = %v, want a conflict", err)
+	}
+	if uploads != 0 {
+		t.Errorf("uploaded %d times after a failed conflict check", uploads)
+	}
+
+	result, err := h.HandleUpload(context.Background(), json.RawMessage(`{"path":"/notes.txt","content":"hello"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	if item["renamed"] != true || item["renamed_from"] != "/notes.txt" || item["path"] != "/notes (1).txt" || item["conflict"] != nil {
+		t.Errorf("HandleUpload() = %v, want a rename from /notes.txt", item)
+	}
+}
+
+func TestHandleUploadRenamedWithIDPath(t *testing.T) {
+	saved := fileEntry("/Docs/notes.txt", 5)
+	saved.Id = "id:a4ayc_80_OEAAAAAAAAAXw"
+	h := newTestHandler(&fakeFiles{
+		upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+			return saved, nil
+		},
+	})
+
+	for _, p := range []string{"id:a4ayc_80_OEAAAAAAAAAXw", "ns:1234/Docs/notes.txt"} {
+		result, err := h.HandleUpload(context.Background(),
+			json.RawMessage(`{"path":"`+p+`","content":"hello","mode":"update","rev":"015f"}`))
+		if err != nil {
+			t.Fatalf("HandleUpload(%s) error = %v", p, err)
+		}
+		item := result.(map[string]interface{})
+		if item["renamed"] != false || item["conflict"] != nil || item["message"] != nil {
+			t.Errorf("HandleUpload(%s) = %v, want no rename or conflict", p, item)
+		}
+	}
+
+	saved = fileEntry("/Docs/notes (conflicted copy).txt", 5)
+	saved.Id = "id:b5bzd_91_PFBBBBBBBBBBYx"
+	result, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"id:a4ayc_80_OEAAAAAAAAAXw","content":"hello","mode":"update","rev":"015f"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	if item := result.(map[string]interface{}); item["renamed"] != true || item["conflict"] != true {
+		t.Errorf("HandleUpload() = %v, want a conflict", item)
+	}
+}
+
+func TestHandleUploadAutorenameDefault(t *testing.T) {
+	var autorename bool
+	h := newTestHandler(&fakeFiles{
+		upload: func(arg *files.UploadArg, content io.Reader) (*files.FileMetadata, error) {
+			autorename = arg.Autorename
+			return fileEntry(arg.Path, 5), nil
+		},
+	})
+	off := false
+	h.config.UploadAutorename = &off
+
+	result, err := h.HandleUpload(context.Background(), json.RawMessage(`{"path":"/notes.txt","content":"hello"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	if autorename {
+		t.Error("Autorename = true, want the configured default of false")
+	}
+	item := result.(map[string]interface{})
+	if item["renamed"] != false || item["path"] != "/notes.txt" || item["renamed_from"] != nil {
+		t.Errorf("HandleUpload() = %v, want no rename", item)
+	}
+
+	if _, err := h.HandleUpload(context.Background(),
+		json.RawMessage(`{"path":"/notes.txt","content":"hello","autorename":true}`)); err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	if !autorename {
+		t.Error("Autorename = false, want the explicit true")
+	}
+}
+
+func TestHandleMoveSharedFolder(t *testing.T) {
+	var moves int
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			folder := folderEntry("/Team")
+			folder.SharingInfo = &files.FolderSharingInfo{SharedFolderId: "84528192421"}
+			return folder, nil
+		},
+		moveV2: func(arg *files.RelocationArg) (*files.RelocationResult, error) {
+			moves++
+			return &files.RelocationResult{Metadata: folderEntry(arg.ToPath)}, nil
+		},
+	})
+
+	_, err := h.HandleMove(context.Background(), json.RawMessage(`{"from_path":"/Team","to_path":"/Archive/Team"}`))
+	if err == nil || !strings.Contains(err.Error(), "allow_shared_folder") {
+		t.Errorf("HandleMove() error = %v, want a shared folder warning", err)
+	}
+	if moves != 0 {
+		t.Errorf("moved %d times without allow_shared_folder", moves)
+	}
+
+	result, err := h.HandleMove(context.Background(),
+		json.RawMessage(`{"from_path":"/Team","to_path":"/Archive/Team","allow_shared_folder":true}`))
+	if err != nil {
+		t.Fatalf("HandleMove() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	if item["path"] != "/Archive/Team" || item["shared_folder_id"] != "84528192421" {
+		t.Errorf("HandleMove() = %v, want the shared folder moved", item)
+	}
+}
+
+func TestHandleMoveOverwrite(t *testing.T) {
+	conflict := func(tag string) error {
+		return files.MoveV2APIError{EndpointError: &files.RelocationError{
+			Tagged: sdk.Tagged{Tag: files.RelocationErrorTo},
+			To: &files.WriteError{
+				Tagged:   sdk.Tagged{Tag: files.WriteErrorConflict},
+				Conflict: &files.WriteConflictError{Tagged: sdk.Tagged{Tag: tag}},
+			},
+		}}
+	}
+
+	existing := map[string]string{
+		"/docs/old.txt": files.WriteConflictErrorFile,
+		"/docs/old":     files.WriteConflictErrorFolder,
+	}
+	var deleted []string
+	var moves [][2]string
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return fileEntry("/Docs/File.txt", 5), nil
+		},
+		moveV2: func(arg *files.RelocationArg) (*files.RelocationResult, error) {
+			moves = append(moves, [2]string{arg.FromPath, arg.ToPath})
+			if tag, ok := existing[strings.ToLower(arg.ToPath)]; ok {
+				return nil, conflict(tag)
+			}
+			// The source itself is found at a case-only destination
+			if strings.HasPrefix(arg.FromPath, "id:") && strings.EqualFold(arg.ToPath, "/Docs/File.txt") {
+				return nil, conflict(files.WriteConflictErrorFile)
+			}
+			return &files.RelocationResult{Metadata: fileEntry(arg.ToPath, 5)}, nil
+		},
+		deleteV2: func(arg *files.DeleteArg) (*files.DeleteResult, error) {
+			deleted = append(deleted, arg.Path)
+			delete(existing, strings.ToLower(arg.Path))
+			return &files.DeleteResult{}, nil
+		},
+	})
+
+	result, err := h.HandleMove(context.Background(),
+		json.RawMessage(`{"from_path":"/Docs/File.txt","to_path":"/Docs/Old.txt","overwrite":true}`))
+	if err != nil {
+		t.Fatalf("HandleMove() error = %v", err)
+	}
+	if item := result.(map[string]interface{}); item["replaced"] != true || len(deleted) != 1 || deleted[0] != "/Docs/Old.txt" {
+		t.Errorf("HandleMove() = %v, deleted %v, want the file at the destination replaced", item, deleted)
+	}
+
+	deleted = nil
+	_, err = h.HandleMove(context.Background(),
+		json.RawMessage(`{"from_path":"/Docs/File.txt","to_path":"/Docs/Old","overwrite":true}`))
+	if err == nil || !strings.Contains(err.Error(), "is a folder") {
+		t.Errorf("HandleMove() error = %v, want the folder conflict reported", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("deleted %v, want a folder at the destination kept", deleted)
+	}
+
+	moves = nil
+	result, err = h.HandleMove(context.Background(),
+		json.RawMessage(`{"from_path":"id:a4ayc_80_OEAAAAAAAAAXw","to_path":"/Docs/file.txt","overwrite":true}`))
+	if err != nil {
+		t.Fatalf("HandleMove() error = %v", err)
+	}
+	if item := result.(map[string]interface{}); item["path"] != "/Docs/file.txt" || item["replaced"] != false {
+		t.Errorf("HandleMove() = %v, want a case-only move", item)
+	}
+	if len(deleted) != 0 || len(moves) != 2 || moves[0][0] != "/Docs/File.txt" {
+		t.Errorf("moves = %v, deleted %v, want a move through a temporary name", moves, deleted)
+	}
+}
+
+func TestSharedFolderGuards(t *testing.T) {
+	var moves int
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			if arg.Path != "/Team" {
+				return fileEntry(arg.Path, 1), nil
+			}
+			folder := folderEntry("/Team")
+			folder.SharingInfo = &files.FolderSharingInfo{SharedFolderId: "84528192421"}
+			return folder, nil
+		},
+		moveV2: func(arg *files.RelocationArg) (*files.RelocationResult, error) {
+			moves++
+			return &files.RelocationResult{Metadata: folderEntry(arg.ToPath)}, nil
+		},
+		moveBatchV2: func(arg *files.MoveBatchArg) (*files.RelocationBatchV2Launch, error) {
+			moves++
+			entries := make([]*files.RelocationBatchResultEntry, 0, len(arg.Entries))
+			for _, entry := range arg.Entries {
+				entries = append(entries, &files.RelocationBatchResultEntry{Success: fileEntry(entry.ToPath, 1)})
+			}
+			return &files.RelocationBatchV2Launch{
+				Tagged:   sdk.Tagged{Tag: files.RelocationBatchV2LaunchComplete},
+				Complete: &files.RelocationBatchV2Result{Entries: entries},
+			}, nil
+		},
+	})
+
+	const entries = `"entries":[{"from_path":"/a.txt","to_path":"/x/a.txt"},{"from_path":"/Team","to_path":"/x/Team"}]`
+	for name, handle := range map[string]func(context.Context, json.RawMessage) (interface{}, error){
+		"HandleRename":                h.HandleRename,
+		"HandleMoveBatch":             h.HandleMoveBatch,
+		"HandleMoveBatchWithProgress": h.HandleMoveBatchWithProgress,
+	} {
+		params := `{` + entries + `}`
+		allowed := `{` + entries + `,"allow_shared_folder":true}`
+		if name == "HandleRename" {
+			params = `{"path":"/Team","new_name":"Team Archive"}`
+			allowed = `{"path":"/Team","new_name":"Team Archive","allow_shared_folder":true}`
+		}
+
+		moves = 0
+		_, err := handle(context.Background(), json.RawMessage(params))
+		if err == nil || !strings.Contains(err.Error(), "allow_shared_folder") {
+			t.Errorf("%s() error = %v, want a shared folder warning", name, err)
+		}
+		if moves != 0 {
+			t.Errorf("%s() moved without allow_shared_folder", name)
+		}
+
+		if _, err := handle(context.Background(), json.RawMessage(allowed)); err != nil {
+			t.Errorf("%s() with allow_shared_folder error = %v", name, err)
+		}
+		if moves != 1 {
+			t.Errorf("%s() moved %d times with allow_shared_folder, want 1", name, moves)
+		}
+	}
+}
+
+func TestHandleRename(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return fileEntry("/Docs/draft.txt", 5), nil
+		},
+		moveV2: func(arg *files.RelocationArg) (*files.RelocationResult, error) {
+			if arg.ToPath != "/Docs/final.txt" {
+				t.Errorf("ToPath = %q, want /Docs/final.txt", arg.ToPath)
+			}
+			return &files.RelocationResult{Metadata: fileEntry(arg.ToPath, 5)}, nil
+		},
+	})
+
+	for _, from := range []string{"/Docs/draft.txt", "id:a4ayc_80_OEAAAAAAAAAXw"} {
+		params, _ := json.Marshal(map[string]string{"path": from, "new_name": "final.txt"})
+		result, err := h.HandleRename(context.Background(), params)
+		if err != nil {
+			t.Fatalf("HandleRename(%s) error = %v", from, err)
+		}
+		item := result.(map[string]interface{})
+		if item["path"] != "/Docs/final.txt" || item["renamed_from"] != "/Docs/draft.txt" {
+			t.Errorf("HandleRename(%s) = %v, want /Docs/draft.txt renamed to /Docs/final.txt", from, item)
+		}
+	}
+
+	for _, params := range []string{
+		`{"path":"/Docs/draft.txt","new_name":"Archive/final.txt"}`,
+		`{"path":"/Docs/draft.txt","new_name":".."}`,
+		`{"path":"/","new_name":"x"}`,
+	} {
+		if _, err := h.HandleRename(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("HandleRename(%s) succeeded, want an error", params)
+		}
+	}
+}
+
+func TestHandleRenameCaseOnly(t *testing.T) {
+	var moves [][2]string
+	failFinal := false
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return fileEntry("/Docs/File.txt", 5), nil
+		},
+		moveV2: func(arg *files.RelocationArg) (*files.RelocationResult, error) {
+			moves = append(moves, [2]string{arg.FromPath, arg.ToPath})
+			if failFinal && arg.ToPath == "/Docs/file.txt" {
+				return nil, errors.New("too many write operations")
+			}
+			return &files.RelocationResult{Metadata: fileEntry(arg.ToPath, 5)}, nil
+		},
+	})
+
+	result, err := h.HandleRename(context.Background(), json.RawMessage(`{"path":"id:a4ayc_80_OEAAAAAAAAAXw","new_name":"file.txt"}`))
+	if err != nil {
+		t.Fatalf("HandleRename() error = %v", err)
+	}
+	if item := result.(map[string]interface{}); item["path"] != "/Docs/file.txt" {
+		t.Errorf("HandleRename() path = %v, want /Docs/file.txt", item["path"])
+	}
+	if len(moves) != 2 || moves[0][0] != "/Docs/File.txt" || !strings.HasPrefix(moves[0][1], "/Docs/File.txt.case-rename-") ||
+		moves[1][0] != moves[0][1] || moves[1][1] != "/Docs/file.txt" {
+		t.Errorf("moves = %v, want a move through a temporary name", moves)
+	}
+
+	moves, failFinal = nil, true
+	if _, err := h.HandleMove(context.Background(), json.RawMessage(`{"from_path":"/Docs/File.txt","to_path":"/Docs/file.txt"}`)); err == nil {
+		t.Fatal("HandleMove() succeeded, want an error")
+	}
+	if len(moves) != 3 || moves[2][0] != moves[0][1] || moves[2][1] != "/Docs/File.txt" {
+		t.Errorf("moves = %v, want the item moved back from the temporary name", moves)
+	}
+}
+
+func TestHandleFindByTag(t *testing.T) {
+	entries := []files.IsMetadata{folderEntry("/Projects")}
+	for i := 0; i < 150; i++ {
+		entries = append(entries, fileEntry(fmt.Sprintf("/Projects/file-%03d.txt", i), 1))
+	}
+
+	var mu sync.Mutex
+	var requests []int
+	h := newTestHandler(&fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			return &files.ListFolderResult{Entries: entries}, nil
+		},
+		tagsGet: func(arg *files.GetTagsArg) (*files.GetTagsResult, error) {
+			mu.Lock()
+			requests = append(requests, len(arg.Paths))
+			mu.Unlock()
+
+			result := &files.GetTagsResult{}
+			for _, path := range arg.Paths {
+				tag := "draft"
+				if path == "/projects/file-007.txt" || path == "/projects/file-120.txt" {
+					tag = "urgent"
+				}
+				result.PathsToTags = append(result.PathsToTags, &files.PathToTags{Path: path, Tags: []*files.Tag{
+					{Tagged: sdk.Tagged{Tag: files.TagUserGeneratedTag}, UserGeneratedTag: &files.UserGeneratedTag{TagText: tag}},
+				}})
+			}
+			return result, nil
+		},
+	})
+
+	result, err := h.HandleFindByTag(context.Background(), json.RawMessage(`{"path":"/Projects","tag_text":"#Urgent"}`))
+	if err != nil {
+		t.Fatalf("HandleFindByTag() error = %v", err)
+	}
+	res := result.(map[string]interface{})
+	var paths []string
+	for _, item := range res["files"].([]map[string]interface{}) {
+		paths = append(paths, item["path"].(string))
+	}
+	if want := []string{"/Projects/file-007.txt", "/Projects/file-120.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("HandleFindByTag() files = %v, want %v", paths, want)
+	}
+	if res["scanned"] != 150 || res["tag_text"] != "Urgent" {
+		t.Errorf("HandleFindByTag() = %v, want 150 files scanned for Urgent", res)
+	}
+	sort.Ints(requests)
+	if !reflect.DeepEqual(requests, []int{50, 100}) {
+		t.Errorf("tag requests covered %v paths, want batches of 100 and 50", requests)
+	}
+
+	if _, err := h.HandleFindByTag(context.Background(), json.RawMessage(`{"path":"/Projects","tag_text":"#"}`)); err == nil {
+		t.Error("HandleFindByTag() without a tag succeeded, want an error")
+	}
+}
+
+func TestHandleListPattern(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			return &files.ListFolderResult{Entries: []files.IsMetadata{
+				folderEntry("/Docs/Reports"),
+				fileEntry("/Docs/Reports/report-2024.PDF", 1),
+				fileEntry("/Docs/notes.txt", 1),
+				fileEntry("/Docs/summary.pdf", 1),
+			}}, nil
+		},
+	})
+
+	tests := []struct {
+		params string
+		want   []string
+	}{
+		{`{"path":"/Docs","recursive":true,"pattern":"*.pdf"}`, []string{"summary.pdf"}},
+		{`{"path":"/Docs","recursive":true,"pattern":"*.pdf","ignore_case":true}`, []string{"report-2024.PDF", "summary.pdf"}},
+		{`{"path":"/Docs","recursive":true,"pattern":"Report?"}`, []string{"Reports"}},
+	}
+	for _, tt := range tests {
+		result, err := h.HandleList(context.Background(), json.RawMessage(tt.params))
+		if err != nil {
+			t.Fatalf("HandleList(%s) error = %v", tt.params, err)
+		}
+		var names []string
+		for _, item := range result.([]map[string]interface{}) {
+			names = append(names, item["name"].(string))
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("HandleList(%s) names = %v, want %v", tt.params, names, tt.want)
+		}
+	}
+
+	if _, err := h.HandleList(context.Background(), json.RawMessage(`{"pattern":"[a-"}`)); err == nil {
+		t.Error("HandleList() with an invalid pattern succeeded, want an error")
+	}
+}
+
+func TestEntryFilterModified(t *testing.T) {
+	file := func(name, modified string) *files.FileMetadata {
+		entry := fileEntry("/"+name, 1)
+		entry.ServerModified, _ = time.Parse(time.RFC3339, modified)
+		return entry
+	}
+	entries := []files.IsMetadata{
+		file("old.txt", "2024-01-01T00:00:00Z"),
+		file("recent.txt", "2024-06-10T12:00:00Z"),
+		file("new.txt", "2024-07-01T00:00:00Z"),
+		folderEntry("/Archive"),
+	}
+
+	tests := []struct {
+		after, before  string
+		includeFolders bool
+		want           []string
+	}{
+		{"2024-06-01T00:00:00Z", "", false, []string{"/recent.txt", "/new.txt"}},
+		{"2024-06-01T00:00:00Z", "2024-07-01T00:00:00Z", false, []string{"/recent.txt"}},
+		{"", "2024-06-01T00:00:00Z", true, []string{"/old.txt", "/Archive"}},
+		{"", "", false, []string{"/old.txt", "/recent.txt", "/new.txt", "/Archive"}},
+	}
+	for _, tt := range tests {
+		filter, err := newEntryFilter("", false, tt.after, tt.before, tt.includeFolders)
+		if err != nil {
+			t.Fatalf("newEntryFilter(%q, %q) error = %v", tt.after, tt.before, err)
+		}
+		var got []string
+		for _, item := range listItems(filter.apply(entries)) {
+			got = append(got, item["path"].(string))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filter(%q, %q) = %v, want %v", tt.after, tt.before, got, tt.want)
+		}
+	}
+
+	for _, times := range [][2]string{{"yesterday", ""}, {"", "2024-13-01T00:00:00Z"}, {"2024-07-01T00:00:00Z", "2024-06-01T00:00:00Z"}} {
+		if _, err := newEntryFilter("", false, times[0], times[1], false); err == nil {
+			t.Errorf("newEntryFilter(%q, %q) succeeded, want an error", times[0], times[1])
+		}
+	}
+}
+
+func TestHandleSearchPages(t *testing.T) {
+	match := func(entry files.IsMetadata) *files.SearchMatchV2 {
+		return &files.SearchMatchV2{Metadata: &files.MetadataV2{Metadata: entry}}
+	}
+	h := newTestHandler(&fakeFiles{
+		searchV2: func(arg *files.SearchV2Arg) (*files.SearchV2Result, error) {
+			return &files.SearchV2Result{
+				Matches: []*files.SearchMatchV2{match(fileEntry("/a.txt", 1))},
+				HasMore: true,
+				Cursor:  "page2",
+			}, nil
+		},
+		searchContinueV2: func(arg *files.SearchV2ContinueArg) (*files.SearchV2Result, error) {
+			if arg.Cursor != "page2" {
+				t.Errorf("Cursor = %q, want page2", arg.Cursor)
+			}
+			return &files.SearchV2Result{
+				Matches: []*files.SearchMatchV2{match(fileEntry("/b.txt", 1))},
+				Cursor:  "done",
+			}, nil
+		},
+	})
+
+	result, err := h.HandleSearch(context.Background(), json.RawMessage(`{"query":"txt"}`))
+	if err != nil {
+		t.Fatalf("HandleSearch() error = %v", err)
+	}
+	page := result.(map[string]interface{})
+	if page["has_more"] != true || page["next_cursor"] != "page2" || len(page["matches"].([]map[string]interface{})) != 1 {
+		t.Errorf("HandleSearch() = %v, want one match and a cursor", page)
+	}
+
+	result, err = h.HandleSearchContinue(context.Background(), json.RawMessage(`{"cursor":"page2"}`))
+	if err != nil {
+		t.Fatalf("HandleSearchContinue() error = %v", err)
+	}
+	page = result.(map[string]interface{})
+	if _, ok := page["next_cursor"]; ok || page["has_more"] != false {
+		t.Errorf("HandleSearchContinue() = %v, want the last page", page)
+	}
+	if matches := page["matches"].([]map[string]interface{}); len(matches) != 1 || matches[0]["path"] != "/b.txt" {
+		t.Errorf("matches = %v, want /b.txt", matches)
+	}
+}
+
+func TestHandleSearchOrderBy(t *testing.T) {
+	var orderBy string
+	h := newTestHandler(&fakeFiles{
+		searchV2: func(arg *files.SearchV2Arg) (*files.SearchV2Result, error) {
+			orderBy = ""
+			if arg.Options.OrderBy != nil {
+				orderBy = arg.Options.OrderBy.Tag
+			}
+			return &files.SearchV2Result{}, nil
+		},
+	})
+
+	if _, err := h.HandleSearch(context.Background(), json.RawMessage(`{"query":"invoice","order_by":"last_modified_time"}`)); err != nil {
+		t.Fatalf("HandleSearch() error = %v", err)
+	}
+	if orderBy != files.SearchOrderByLastModifiedTime {
+		t.Errorf("OrderBy = %q, want %q", orderBy, files.SearchOrderByLastModifiedTime)
+	}
+
+	if _, err := h.HandleSearch(context.Background(), json.RawMessage(`{"query":"invoice","order_by":"size"}`)); err == nil {
+		t.Error("HandleSearch() with an invalid order_by succeeded, want an error")
+	}
+}
+
+func TestHandleMoveBatchWithProgress(t *testing.T) {
+	var jobs [][]string
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return fileEntry(arg.Path, 1), nil
+		},
+		moveBatchV2: func(arg *files.MoveBatchArg) (*files.RelocationBatchV2Launch, error) {
+			var paths []string
+			entries := make([]*files.RelocationBatchResultEntry, 0, len(arg.Entries))
+			for _, entry := range arg.Entries {
+				paths = append(paths, entry.FromPath)
+				entries = append(entries, &files.RelocationBatchResultEntry{Success: fileEntry(entry.ToPath, 1)})
+			}
+			jobs = append(jobs, paths)
+			return &files.RelocationBatchV2Launch{
+				Tagged:   sdk.Tagged{Tag: files.RelocationBatchV2LaunchComplete},
+				Complete: &files.RelocationBatchV2Result{Entries: entries},
+			}, nil
+		},
+	})
+
+	var progress []string
+	ctx := WithProgress(context.Background(), func(done, total int, message string) {
+		progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, message))
+	})
+
+	params := `{"chunk_size":2,"entries":[` +
+		`{"from_path":"/a","to_path":"/x/a"},{"from_path":"/b","to_path":"/x/b"},{"from_path":"/c","to_path":"/x/c"},` +
+		`{"from_path":"/d","to_path":"/x/d"},{"from_path":"/e","to_path":"/x/e"}]}`
+	result, err := h.HandleMoveBatchWithProgress(ctx, json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("HandleMoveBatchWithProgress() error = %v", err)
+	}
+
+	if len(jobs) != 3 || len(jobs[0]) != 2 || len(jobs[2]) != 1 || jobs[2][0] != "/e" {
+		t.Errorf("batch jobs = %v, want chunks of 2, 2 and 1 entries", jobs)
+	}
+	want := []string{"2/5 2/5 moved", "4/5 4/5 moved", "5/5 5/5 moved"}
+	if !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+
+	res := result.(map[string]interface{})
+	if res["status"] != dropbox.JobComplete || res["completed"] != 5 || res["failed"] != 0 {
+		t.Errorf("HandleMoveBatchWithProgress() = %v, want 5 completed entries", res)
+	}
+	if entries := res["entries"].([]map[string]interface{}); entries[4]["name"] != "e" || entries[4]["success"] != true {
+		t.Errorf("entries[4] = %v, want the moved /x/e", entries[4])
+	}
+}
+
+func TestHandleListPages(t *testing.T) {
+	var limit uint32
+	h := newTestHandler(&fakeFiles{
+		listFolder: func(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+			limit = arg.Limit
+			return &files.ListFolderResult{Entries: []files.IsMetadata{fileEntry("/a.txt", 1)}, Cursor: "page2", HasMore: true}, nil
+		},
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			if arg.Cursor != "page2" {
+				t.Errorf("cursor = %q, want page2", arg.Cursor)
+			}
+			return &files.ListFolderResult{Entries: []files.IsMetadata{fileEntry("/b.txt", 2)}, Cursor: "done"}, nil
+		},
+	})
+
+	result, err := h.HandleList(context.Background(), json.RawMessage(`{"path":"","limit":1}`))
+	if err != nil {
+		t.Fatalf("HandleList() error = %v", err)
+	}
+	page := result.(map[string]interface{})
+	if limit != 1 || page["has_more"] != true || page["next_cursor"] != "page2" || len(page["entries"].([]map[string]interface{})) != 1 {
+		t.Errorf("HandleList() = %v with limit %d, want one entry and a cursor", page, limit)
+	}
+
+	result, err = h.HandleListContinue(context.Background(), json.RawMessage(`{"cursor":"page2"}`))
+	if err != nil {
+		t.Fatalf("HandleListContinue() error = %v", err)
+	}
+	page = result.(map[string]interface{})
+	if _, ok := page["next_cursor"]; ok || page["has_more"] != false {
+		t.Errorf("HandleListContinue() = %v, want the last page", page)
+	}
+	if entries := page["entries"].([]map[string]interface{}); len(entries) != 1 || entries[0]["path"] != "/b.txt" {
+		t.Errorf("entries = %v, want /b.txt", entries)
+	}
+
+	if _, err := h.HandleList(context.Background(), json.RawMessage(`{"path":"","limit":10,"output":"tree"}`)); err == nil {
+		t.Error("HandleList() with limit and tree output succeeded, want an error")
+	}
+}
+
+func TestHandleListContinueFilter(t *testing.T) {
+	h := newTestHandler(&fakeFiles{
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			old := fileEntry("/old.pdf", 1)
+			old.ServerModified = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			recent := fileEntry("/recent.pdf", 2)
+			recent.ServerModified = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+			return &files.ListFolderResult{Entries: []files.IsMetadata{
+				old, recent, fileEntry("/notes.txt", 3), folderEntry("/Reports.pdf"),
+			}}, nil
+		},
+	})
+
+	result, err := h.HandleListContinue(context.Background(),
+		json.RawMessage(`{"cursor":"page2","pattern":"*.PDF","ignore_case":true,"modified_after":"2024-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("HandleListContinue() error = %v", err)
+	}
+	entries := result.(map[string]interface{})["entries"].([]map[string]interface{})
+	if len(entries) != 1 || entries[0]["path"] != "/recent.pdf" {
+		t.Errorf("entries = %v, want only /recent.pdf", entries)
+	}
+
+	if _, err := h.HandleListContinue(context.Background(), json.RawMessage(`{"cursor":"page2","pattern":"["}`)); err == nil {
+		t.Error("HandleListContinue() with an invalid pattern succeeded, want an error")
+	}
+}
+
+func TestHandleReadRangeEmptyFile(t *testing.T) {
+	// Reading an empty file must not download anything, which would panic
+	h := newTestHandler(&fakeFiles{
+		getMetadata: func(arg *files.GetMetadataArg) (files.IsMetadata, error) {
+			return fileEntry(arg.Path, 0), nil
+		},
+	})
+
+	result, err := h.HandleReadRange(context.Background(), json.RawMessage(`{"path":"/empty.txt","length":100}`))
+	if err != nil {
+		t.Fatalf("HandleReadRange() error = %v", err)
+	}
+	item := result.(map[string]interface{})
+	if item["eof"] != true || item["length"] != 0 || item["content"] != "" {
+		t.Errorf("HandleReadRange() = %v, want an empty result at eof", item)
+	}
+
+	_, err = h.HandleReadRange(context.Background(), json.RawMessage(`{"path":"/empty.txt","offset":1,"length":100}`))
+	if err == nil || !strings.Contains(err.Error(), "beyond the end") {
+		t.Errorf("HandleReadRange(offset 1) error = %v, want offset beyond the end", err)
+	}
+}
+
+func TestHandleDownloadZip(t *testing.T) {
+	archive := "PK\x03\x04 zip content"
+	h := newTestHandler(&fakeFiles{
+		downloadZip: func(arg *files.DownloadZipArg) (*files.DownloadZipResult, io.ReadCloser, error) {
+			if arg.Path == "/Huge" {
+				return nil, nil, files.DownloadZipAPIError{
+					EndpointError: &files.DownloadZipError{Tagged: sdk.Tagged{Tag: files.DownloadZipErrorTooManyFiles}},
+				}
+			}
+			folder := &files.FolderMetadata{Metadata: files.Metadata{Name: "Photos", PathDisplay: "/Photos"}}
+			return &files.DownloadZipResult{Metadata: folder}, io.NopCloser(strings.NewReader(archive)), nil
+		},
+	})
+
+	result, err := h.HandleDownloadZip(context.Background(), json.RawMessage(`{"path":"/photos"}`))
+	if err != nil {
+		t.Fatalf("HandleDownloadZip() error = %v", err)
+	}
+	res := result.(map[string]interface{})
+	if res["path"] != "/Photos" || res["content"] != base64.StdEncoding.EncodeToString([]byte(archive)) {
+		t.Errorf("HandleDownloadZip() = %v, want the base64 archive of /Photos", res)
+	}
+
+	h.config.TempDir = t.TempDir()
+	local := filepath.Join(t.TempDir(), "photos.zip")
+	if _, err := h.HandleDownloadZip(context.Background(), json.RawMessage(`{"path":"/photos","local_path":`+strconv.Quote(local)+`}`)); err != nil {
+		t.Fatalf("HandleDownloadZip() to local_path error = %v", err)
+	}
+	if data, err := os.ReadFile(local); err != nil || string(data) != archive {
+		t.Errorf("saved archive = %q, %v, want %q", data, err, archive)
+	}
+	if leftover, _ := os.ReadDir(h.config.TempDir); len(leftover) != 0 {
+		t.Errorf("temp directory holds %d files after the download, want none", len(leftover))
+	}
+
+	if _, err := h.HandleDownloadZip(context.Background(), json.RawMessage(`{"path":"/photos","max_bytes":4}`)); err == nil || !strings.Contains(err.Error(), "local_path") {
+		t.Errorf("HandleDownloadZip() over max_bytes error = %v, want a download limit error", err)
+	}
+	if _, err := h.HandleDownloadZip(context.Background(), json.RawMessage(`{"path":"/Huge"}`)); err == nil || !strings.Contains(err.Error(), "dropbox_sync_down") {
+		t.Errorf("HandleDownloadZip() of a huge folder error = %v, want a hint to use dropbox_sync_down", err)
+	}
+}
+
+func TestHandleUploadFromSourceURL(t *testing.T) {
+	const content = "%PDF-1.7 report"
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	}))
+	defer source.Close()
+
+	var uploaded string
+	h := newTestHandler(&fakeFiles{
+		upload: func(arg *files.UploadArg, r io.Reader) (*files.FileMetadata, error) {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+			uploaded = string(data)
+			return fileEntry(arg.Path, uint64(len(data))), nil
+		},
+	})
+
+	result, err := h.HandleUpload(context.Background(), json.RawMessage(`{"path":"/report.pdf","source_url":"`+source.URL+`/report"}`))
+	if err != nil {
+		t.Fatalf("HandleUpload() error = %v", err)
+	}
+	if uploaded != content {
+		t.Errorf("uploaded %q, want %q", uploaded, content)
+	}
+	if res := result.(map[string]interface{}); res["mime_type"] != "application/pdf" {
+		t.Errorf("mime_type = %v, want application/pdf", res["mime_type"])
+	}
+
+	for _, params := range []string{
+		`{"path":"/report.pdf","source_url":"ftp://example.com/report.pdf"}`,
+		`{"path":"/report.pdf","source_url":"` + source.URL + `/missing"}`,
+		`{"path":"/report.pdf","source_url":"` + source.URL + `/report","max_bytes":4}`,
+		`{"path":"/report.pdf","source_url":"` + source.URL + `/report","content":"inline"}`,
+	} {
+		if _, err := h.HandleUpload(context.Background(), json.RawMessage(params)); err == nil {
+			t.Errorf("HandleUpload(%s) succeeded, want an error", params)
+		}
+	}
+}
+
+func TestHandleWatch(t *testing.T) {
+	changes := false
+	h := newTestHandler(&fakeFiles{
+		getLatestCursor: func(arg *files.ListFolderArg) (*files.ListFolderGetLatestCursorResult, error) {
+			if arg.Path != "/Inbox" || !arg.Recursive {
+				t.Errorf("latest cursor of %q (recursive %t), want /Inbox recursively", arg.Path, arg.Recursive)
+			}
+			return &files.ListFolderGetLatestCursorResult{Cursor: "c1"}, nil
+		},
+		longpoll: func(arg *files.ListFolderLongpollArg) (*files.ListFolderLongpollResult, error) {
+			if arg.Cursor != "c1" || arg.Timeout != 60 {
+				t.Errorf("longpoll(%q, %d), want c1 for 60 seconds", arg.Cursor, arg.Timeout)
+			}
+			return &files.ListFolderLongpollResult{Changes: changes}, nil
+		},
+		listFolderContinue: func(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+			return &files.ListFolderResult{Entries: []files.IsMetadata{fileEntry("/Inbox/new.txt", 3)}, Cursor: "c2"}, nil
+		},
+	})
+
+	params := json.RawMessage(`{"path":"/Inbox","recursive":true,"timeout":60}`)
+	result, err := h.HandleWatch(context.Background(), params)
+	if err != nil {
+		t.Fatalf("HandleWatch() error = %v", err)
+	}
+	res := result.(map[string]interface{})
+	if res["timed_out"] != true || res["cursor"] != "c1" || len(res["changes"].([]map[string]interface{})) != 0 {
+		t.Errorf("HandleWatch() without changes = %v, want a timeout and the same cursor", res)
+	}
+
+	changes = true
+	result, err = h.HandleWatch(context.Background(), json.RawMessage(`{"cursor":"c1","timeout":60}`))
+	if err != nil {
+		t.Fatalf("HandleWatch() error = %v", err)
+	}
+	res = result.(map[string]interface{})
+	if entries := res["changes"].([]map[string]interface{}); len(entries) != 1 || entries[0]["path"] != "/Inbox/new.txt" || res["cursor"] != "c2" {
+		t.Errorf("HandleWatch() with changes = %v, want /Inbox/new.txt and cursor c2", res)
+	}
+
+	if _, err := h.HandleWatch(context.Background(), json.RawMessage(`{"timeout":10}`)); err == nil {
+		t.Error("HandleWatch() with a 10 second timeout succeeded, want an error")
+	}
+}
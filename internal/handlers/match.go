@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+// entryFilter selects listing and search entries by name and modification
+// time. The zero value keeps every entry.
+type entryFilter struct {
+	// pattern is a glob matched against entry names
+	pattern    string
+	ignoreCase bool
+
+	// modifiedAfter and modifiedBefore bound the server modification time
+	// of files. Folders have none, so includeFolders decides whether they
+	// pass a time filter.
+	modifiedAfter  time.Time
+	modifiedBefore time.Time
+	includeFolders bool
+}
+
+// newEntryFilter validates the filter arguments of a tool call. The times are
+// RFC 3339 and may be empty.
+func newEntryFilter(pattern string, ignoreCase bool, modifiedAfter, modifiedBefore string, includeFolders bool) (*entryFilter, error) {
+	f := &entryFilter{pattern: pattern, ignoreCase: ignoreCase, includeFolders: includeFolders}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if ignoreCase {
+		f.pattern = strings.ToLower(pattern)
+	}
+
+	var err error
+	if modifiedAfter != "" {
+		if f.modifiedAfter, err = time.Parse(time.RFC3339, modifiedAfter); err != nil {
+			return nil, fmt.Errorf("invalid modified_after: %w", err)
+		}
+	}
+	if modifiedBefore != "" {
+		if f.modifiedBefore, err = time.Parse(time.RFC3339, modifiedBefore); err != nil {
+			return nil, fmt.Errorf("invalid modified_before: %w", err)
+		}
+	}
+	if !f.modifiedAfter.IsZero() && !f.modifiedBefore.IsZero() && !f.modifiedAfter.Before(f.modifiedBefore) {
+		return nil, fmt.Errorf("modified_after must be earlier than modified_before")
+	}
+
+	return f, nil
+}
+
+func (f *entryFilter) filtersTime() bool {
+	return !f.modifiedAfter.IsZero() || !f.modifiedBefore.IsZero()
+}
+
+// apply returns the entries the filter keeps.
+func (f *entryFilter) apply(entries []files.IsMetadata) []files.IsMetadata {
+	if f.pattern == "" && !f.filtersTime() {
+		return entries
+	}
+
+	var kept []files.IsMetadata
+	for _, entry := range entries {
+		if f.keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// keep reports whether entry passes the filter.
+func (f *entryFilter) keep(entry files.IsMetadata) bool {
+	var name string
+	var modified time.Time
+	switch e := entry.(type) {
+	case *files.FileMetadata:
+		name, modified = e.Name, e.ServerModified
+	case *files.FolderMetadata:
+		name = e.Name
+	case *files.DeletedMetadata:
+		name = e.Name
+	}
+
+	if f.pattern != "" {
+		if f.ignoreCase {
+			name = strings.ToLower(name)
+		}
+		if ok, _ := path.Match(f.pattern, name); !ok {
+			return false
+		}
+	}
+
+	if !f.filtersTime() {
+		return true
+	}
+	if modified.IsZero() {
+		return f.includeFolders
+	}
+	if !f.modifiedAfter.IsZero() && !modified.After(f.modifiedAfter) {
+		return false
+	}
+	return f.modifiedBefore.IsZero() || modified.Before(f.modifiedBefore)
+}
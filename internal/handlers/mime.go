@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const sniffLen = 512
+
+// mimeTypeByName infers a MIME type from the file extension of name. It
+// returns an empty string when the extension is unknown.
+func mimeTypeByName(name string) string {
+	return mime.TypeByExtension(strings.ToLower(path.Ext(name)))
+}
+
+// detectMimeType infers the MIME type of downloaded content from its name and
+// confirms it by sniffing the first bytes. The extension wins when known, but
+// the sniffed charset is added to text types that lack one.
+func detectMimeType(name string, data []byte) string {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+	sniffed := http.DetectContentType(data)
+
+	byName := mimeTypeByName(name)
+	if byName == "" {
+		return sniffed
+	}
+
+	mediaType, params, err := mime.ParseMediaType(byName)
+	if err != nil || !strings.HasPrefix(mediaType, "text/") || params["charset"] != "" {
+		return byName
+	}
+
+	_, sniffedParams, err := mime.ParseMediaType(sniffed)
+	if err != nil || sniffedParams["charset"] == "" {
+		return byName
+	}
+
+	params["charset"] = sniffedParams["charset"]
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// uploadTypeWarning describes a mismatch between the extension of name and
+// the declared or sniffed type of uploaded content. It returns an empty
+// string when nothing looks wrong.
+func uploadTypeWarning(name, contentType string, data []byte) string {
+	byName, _, err := mime.ParseMediaType(mimeTypeByName(name))
+	if err != nil {
+		// Without a known extension there is nothing to contradict
+		return ""
+	}
+
+	if contentType != "" {
+		declared, _, _ := mime.ParseMediaType(contentType)
+		if declared != byName {
+			return fmt.Sprintf("content_type %s does not match the %s extension, which Dropbox treats as %s",
+				declared, path.Ext(name), byName)
+		}
+		return ""
+	}
+
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	switch {
+	case sniffed == "application/octet-stream":
+		if strings.HasPrefix(byName, "text/") {
+			return fmt.Sprintf("binary content is uploaded to a %s file", path.Ext(name))
+		}
+	case strings.HasPrefix(sniffed, "text/"), sniffed == "application/zip":
+		// Text and zip containers are shared by too many formats to tell
+	case sniffed != byName:
+		return fmt.Sprintf("content looks like %s, but the %s extension suggests %s", sniffed, path.Ext(name), byName)
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// defaultPaperListLimit is how many doc IDs dropbox_list_paper_docs returns
+// when the call does not say.
+const defaultPaperListLimit = 100
+
+// HandleListPaperDocs lists the IDs of the Paper docs the user can access.
+func (h *Handler) HandleListPaperDocs(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		FilterBy  string `json:"filter_by"`
+		SortBy    string `json:"sort_by"`
+		SortOrder string `json:"sort_order"`
+		Limit     int    `json:"limit"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	switch args.FilterBy {
+	case "", "docs_accessed", "docs_created":
+	default:
+		return nil, fmt.Errorf("filter_by must be %q or %q", "docs_accessed", "docs_created")
+	}
+	switch args.SortBy {
+	case "", "accessed", "modified", "created":
+	default:
+		return nil, fmt.Errorf("sort_by must be %q, %q or %q", "accessed", "modified", "created")
+	}
+	switch args.SortOrder {
+	case "", "ascending", "descending":
+	default:
+		return nil, fmt.Errorf("sort_order must be %q or %q", "ascending", "descending")
+	}
+	if args.Limit == 0 {
+		args.Limit = defaultPaperListLimit
+	}
+	if args.Limit < 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	docIDs, err := client.ListPaperDocs(dropbox.PaperListOptions{
+		FilterBy:  args.FilterBy,
+		SortBy:    args.SortBy,
+		SortOrder: args.SortOrder,
+		Limit:     args.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"doc_ids": docIDs,
+		"count":   len(docIDs),
+	}, nil
+}
+
+// HandleCreatePaperDoc creates a Paper doc from markdown, HTML or plain text
+// content. Paper takes the title from the first line of the content.
+func (h *Handler) HandleCreatePaperDoc(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Content        string `json:"content"`
+		Format         string `json:"format"`
+		ParentFolderID string `json:"parent_folder_id"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Content == "" {
+		return nil, fmt.Errorf("content parameter is required")
+	}
+	switch args.Format {
+	case "":
+		args.Format = "markdown"
+	case "markdown", "html", "plain_text":
+	default:
+		return nil, fmt.Errorf("format must be %q, %q or %q", "markdown", "html", "plain_text")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := client.CreatePaperDoc([]byte(args.Content), args.Format, args.ParentFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"doc_id":   doc.DocId,
+		"title":    doc.Title,
+		"revision": doc.Revision,
+	}, nil
+}
+
+// HandleDownloadPaperDoc returns the content of a Paper doc as markdown or
+// HTML.
+func (h *Handler) HandleDownloadPaperDoc(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		DocID  string `json:"doc_id"`
+		Format string `json:"format"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.DocID == "" {
+		return nil, fmt.Errorf("doc_id parameter is required")
+	}
+	switch args.Format {
+	case "":
+		args.Format = "markdown"
+	case "markdown", "html":
+	default:
+		return nil, fmt.Errorf("format must be %q or %q", "markdown", "html")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, data, err := client.DownloadPaperDoc(args.DocID, args.Format, h.config.DownloadLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"doc_id":    args.DocID,
+		"title":     doc.Title,
+		"owner":     doc.Owner,
+		"revision":  doc.Revision,
+		"mime_type": doc.MimeType,
+		"format":    args.Format,
+		"content":   string(data),
+		"type":      "text",
+	}, nil
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import "context"
+
+// ProgressFunc receives progress updates from a long-running tool call, such
+// as "12 of 50 moved".
+type ProgressFunc func(progress, total int, message string)
+
+type progressKey struct{}
+
+// WithProgress returns a context that carries fn, so that tools which support
+// it can report their progress to the client.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// reportProgress sends an update to the ProgressFunc of ctx, if any.
+func reportProgress(ctx context.Context, progress, total int, message string) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(progress, total, message)
+	}
+}
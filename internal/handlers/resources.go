@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+const (
+	resourceScheme      = "dropbox://"
+	resourceURITemplate = "dropbox://{path}"
+)
+
+// ResourceTemplates returns the resource templates exposed by the server.
+func (h *Handler) ResourceTemplates() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"uriTemplate": resourceURITemplate,
+			"name":        "Dropbox path",
+			"description": "A file or folder in Dropbox. Folders are returned as a JSON listing, files as their content.",
+		},
+	}
+}
+
+// ReadResource reads a dropbox:// resource URI. Folders are listed and files
+// are downloaded, depending on the metadata type of the path.
+func (h *Handler) ReadResource(ctx context.Context, uri string) (interface{}, error) {
+	path, err := parseResourceURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The root folder has no metadata, so list it directly.
+	if path == "" {
+		return h.readFolderResource(client, uri, path)
+	}
+
+	metadata, err := client.GetMetadata(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+
+	switch metadata.(type) {
+	case *files.FolderMetadata:
+		return h.readFolderResource(client, uri, path)
+	case *files.FileMetadata:
+		return h.readFileResource(client, uri, path)
+	}
+
+	return nil, fmt.Errorf("resource not found: %s", uri)
+}
+
+func (h *Handler) readFolderResource(client *dropbox.Client, uri, path string) (interface{}, error) {
+	entries, err := client.ListFolder(path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case *files.FileMetadata:
+			items = append(items, map[string]interface{}{
+				"name": e.Name,
+				"path": e.PathDisplay,
+				"type": typeFile,
+				"size": e.Size,
+				"uri":  buildResourceURI(e.PathDisplay),
+			})
+		case *files.FolderMetadata:
+			items = append(items, map[string]interface{}{
+				"name": e.Name,
+				"path": e.PathDisplay,
+				"type": typeFolder,
+				"uri":  buildResourceURI(e.PathDisplay),
+			})
+		}
+	}
+
+	text, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal folder listing: %w", err)
+	}
+
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"uri":      uri,
+				"mimeType": "application/json",
+				"text":     string(text),
+			},
+		},
+	}, nil
+}
+
+func (h *Handler) readFileResource(client *dropbox.Client, uri, path string) (interface{}, error) {
+	data, err := client.Download(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]interface{}{
+		"uri": uri,
+	}
+	content["mimeType"] = detectMimeType(path, data)
+	if isTextContent(data) {
+		content["text"] = string(data)
+	} else {
+		content["blob"] = base64.StdEncoding.EncodeToString(data)
+	}
+
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{content},
+	}, nil
+}
+
+// parseResourceURI converts a dropbox:// URI into a Dropbox path. Both
+// percent-encoded ("dropbox://%2FMy%20Files") and plain
+// ("dropbox:///My Files") forms are accepted, as are IDs and namespace paths
+// ("dropbox://id:abc123"). The root folder is "".
+func parseResourceURI(uri string) (string, error) {
+	if !strings.HasPrefix(uri, resourceScheme) {
+		return "", fmt.Errorf("unsupported resource URI: %s", uri)
+	}
+
+	path, err := url.PathUnescape(strings.TrimPrefix(uri, resourceScheme))
+	if err != nil {
+		return "", fmt.Errorf("invalid resource URI: %w", err)
+	}
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", nil
+	}
+	// IDs and namespace-relative paths are passed through unchanged
+	if _, err := dropbox.ParsePath(path); err == nil {
+		return path, nil
+	}
+	return "/" + path, nil
+}
+
+// buildResourceURI converts a Dropbox path into a dropbox:// URI, escaping
+// each path segment individually so that separators are preserved.
+func buildResourceURI(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return resourceScheme + strings.Join(segments, "/")
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/sharing"
+)
+
+// HandleGetSharedFolderMetadata returns the name, path and collaboration
+// policies of a shared folder.
+func (h *Handler) HandleGetSharedFolderMetadata(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	sharedFolderID, err := sharedFolderIDArg(params)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := client.GetSharedFolderMetadata(sharedFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedFolderItem(folder), nil
+}
+
+// HandleListMountableFolders lists the shared folders the current account
+// can mount or unmount, with mounted telling which are in its Dropbox.
+func (h *Handler) HandleListMountableFolders(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := client.ListMountableFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(folders))
+	for _, folder := range folders {
+		result = append(result, sharedFolderItem(folder))
+	}
+	return result, nil
+}
+
+// HandleMountFolder mounts a shared folder in the current account's Dropbox
+// and returns where it was mounted.
+func (h *Handler) HandleMountFolder(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	sharedFolderID, err := sharedFolderIDArg(params)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := client.MountFolder(sharedFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedFolderItem(folder), nil
+}
+
+// HandleUnmountFolder removes a shared folder from the current account's
+// Dropbox without leaving it.
+func (h *Handler) HandleUnmountFolder(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	sharedFolderID, err := sharedFolderIDArg(params)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.UnmountFolder(sharedFolderID); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"status":           "success",
+		"shared_folder_id": sharedFolderID,
+		"mounted":          false,
+	}, nil
+}
+
+// sharedFolderIDArg parses the arguments of tools that only take a
+// shared_folder_id.
+func sharedFolderIDArg(params json.RawMessage) (string, error) {
+	var args struct {
+		SharedFolderID string `json:"shared_folder_id"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return "", fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.SharedFolderID == "" {
+		return "", fmt.Errorf("shared_folder_id parameter is required")
+	}
+	return args.SharedFolderID, nil
+}
+
+// sharedFolderItem shapes the metadata of a shared folder. The path is
+// omitted for folders that are not mounted in the current account.
+func sharedFolderItem(folder *sharing.SharedFolderMetadata) map[string]interface{} {
+	item := map[string]interface{}{
+		"shared_folder_id":      folder.SharedFolderId,
+		"name":                  folder.Name,
+		"mounted":               folder.PathLower != "",
+		"is_team_folder":        folder.IsTeamFolder,
+		"is_inside_team_folder": folder.IsInsideTeamFolder,
+	}
+	if folder.PathLower != "" {
+		item["path"] = folder.PathLower
+	}
+	if folder.AccessType != nil {
+		item["access_type"] = folder.AccessType.Tag
+	}
+	if len(folder.OwnerDisplayNames) > 0 {
+		item["owners"] = folder.OwnerDisplayNames
+	}
+	if folder.ParentSharedFolderId != "" {
+		item["parent_shared_folder_id"] = folder.ParentSharedFolderId
+	}
+	if folder.Policy != nil {
+		item["policy"] = folderPolicyItem(folder.Policy)
+	}
+	return item
+}
+
+// folderPolicyItem flattens the policies of a shared folder to their tags.
+// Member policies are only set for folders owned by a team.
+func folderPolicyItem(policy *sharing.FolderPolicy) map[string]interface{} {
+	item := map[string]interface{}{}
+	if policy.MemberPolicy != nil {
+		item["member_policy"] = policy.MemberPolicy.Tag
+	}
+	if policy.ResolvedMemberPolicy != nil {
+		item["resolved_member_policy"] = policy.ResolvedMemberPolicy.Tag
+	}
+	if policy.AclUpdatePolicy != nil {
+		item["acl_update_policy"] = policy.AclUpdatePolicy.Tag
+	}
+	if policy.SharedLinkPolicy != nil {
+		item["shared_link_policy"] = policy.SharedLinkPolicy.Tag
+	}
+	if policy.ViewerInfoPolicy != nil {
+		item["viewer_info_policy"] = policy.ViewerInfoPolicy.Tag
+	}
+	return item
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// maxUploadBytes is the largest file Dropbox accepts through an upload
+// session.
+const maxUploadBytes = 350 * 1024 * 1024 * 1024
+
+// sourceClient fetches the content of uploads from a source_url. It honors
+// the HTTP_PROXY and HTTPS_PROXY environment variables.
+var sourceClient = &http.Client{}
+
+// sourceReader counts the bytes read from a source_url and fails once more
+// than limit bytes arrive, so that an upload never commits a file larger than
+// allowed. It keeps the start of the content for type detection.
+type sourceReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	head     []byte
+	exceeded bool
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if room := sniffLen - len(s.head); room > 0 {
+		s.head = append(s.head, p[:min(n, room)]...)
+	}
+	s.read += int64(n)
+	if s.read > s.limit {
+		s.exceeded = true
+		return n, fmt.Errorf("content exceeds %d bytes", s.limit)
+	}
+	return n, err
+}
+
+// openSourceURL starts fetching rawURL, which must be an http or https URL.
+func openSourceURL(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid source_url %q: must be an http or https URL", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source_url: %w", err)
+	}
+	resp, err := sourceClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source_url: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch source_url: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// urlUpload is an upload to path whose content is fetched from url, which
+// may provide at most maxBytes bytes.
+type urlUpload struct {
+	url      string
+	maxBytes int64
+	path     string
+	opts     dropbox.UploadOptions
+}
+
+// run streams the content of the URL to Dropbox without holding it in
+// memory. Content of unknown or large size goes through a chunked upload
+// session. The start of the content is returned for type detection.
+func (u urlUpload) run(ctx context.Context, client *dropbox.Client) (*files.FileMetadata, []byte, error) {
+	maxBytes := u.maxBytes
+	if maxBytes <= 0 || maxBytes > maxUploadBytes {
+		maxBytes = maxUploadBytes
+	}
+
+	resp, err := openSourceURL(ctx, u.url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > maxBytes {
+		return nil, nil, fmt.Errorf("source_url content is %d bytes, which exceeds the %d byte limit", resp.ContentLength, maxBytes)
+	}
+
+	source := &sourceReader{r: resp.Body, limit: maxBytes}
+	metadata, err := client.UploadStream(u.path, source, resp.ContentLength, u.opts)
+	if source.exceeded {
+		return nil, nil, fmt.Errorf("source_url content exceeds the %d byte limit; nothing was saved", maxBytes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata, source.head, nil
+}
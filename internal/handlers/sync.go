@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+const (
+	defaultSyncConcurrency = 4
+	maxSyncConcurrency     = 8
+)
+
+// syncFailure records a file that could not be transferred during a sync.
+type syncFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// syncCounter collects per-file outcomes from concurrent sync workers.
+type syncCounter struct {
+	mu       sync.Mutex
+	counts   map[string]int
+	failures []syncFailure
+}
+
+func newSyncCounter(outcomes ...string) *syncCounter {
+	counts := make(map[string]int, len(outcomes))
+	for _, outcome := range outcomes {
+		counts[outcome] = 0
+	}
+	return &syncCounter{counts: counts}
+}
+
+func (c *syncCounter) add(outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[outcome]++
+}
+
+func (c *syncCounter) fail(path string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts["failed"]++
+	c.failures = append(c.failures, syncFailure{Path: path, Error: err.Error()})
+}
+
+func (c *syncCounter) result() map[string]interface{} {
+	result := make(map[string]interface{}, len(c.counts)+1)
+	for outcome, count := range c.counts {
+		result[outcome] = count
+	}
+	if len(c.failures) > 0 {
+		result["failures"] = c.failures
+	}
+	return result
+}
+
+// runBounded calls fn for each index in [0, n) using at most concurrency
+// goroutines at a time.
+func runBounded(n, concurrency int, fn func(i int)) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func syncConcurrency(requested int) int {
+	if requested <= 0 {
+		return defaultSyncConcurrency
+	}
+	if requested > maxSyncConcurrency {
+		return maxSyncConcurrency
+	}
+	return requested
+}
+
+// localPathFor maps a remote path below remoteRoot to a path below localRoot,
+// rejecting anything that would escape localRoot.
+func localPathFor(localRoot, remoteRoot string, entry *files.FileMetadata) (string, error) {
+	prefix := strings.ToLower(strings.TrimSuffix(remoteRoot, "/"))
+	if !strings.HasPrefix(entry.PathLower, prefix+"/") {
+		return "", fmt.Errorf("%s is outside %s", entry.PathDisplay, remoteRoot)
+	}
+
+	rel := entry.PathDisplay[len(prefix)+1:]
+	target := filepath.Join(localRoot, filepath.FromSlash(rel))
+
+	relToRoot, err := filepath.Rel(localRoot, target)
+	if err != nil || relToRoot == ".." || strings.HasPrefix(relToRoot, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s resolves outside the local directory", entry.PathDisplay)
+	}
+
+	return target, nil
+}
+
+func (h *Handler) HandleSyncDown(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path        string `json:"path"`
+		LocalPath   string `json:"local_path"`
+		Concurrency int    `json:"concurrency"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.LocalPath == "" {
+		return nil, fmt.Errorf("local_path parameter is required")
+	}
+
+	localRoot, err := filepath.Abs(args.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local_path: %w", err)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRoot, err := displayRoot(client, args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := client.ListFolderRecursive(remoteRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	fileEntries := make([]*files.FileMetadata, 0, len(entries))
+	for _, entry := range entries {
+		if file, ok := entry.(*files.FileMetadata); ok && file.IsDownloadable {
+			fileEntries = append(fileEntries, file)
+		}
+	}
+
+	counter := newSyncCounter("downloaded", "skipped", "failed")
+	runBounded(len(fileEntries), syncConcurrency(args.Concurrency), func(i int) {
+		entry := fileEntries[i]
+
+		target, err := localPathFor(localRoot, remoteRoot, entry)
+		if err != nil {
+			counter.fail(entry.PathDisplay, err)
+			return
+		}
+
+		if hash, hashErr := dropbox.FileContentHash(target); hashErr == nil && hash == entry.ContentHash {
+			counter.add("skipped")
+			return
+		}
+
+		if _, err := downloadToFile(client, entry.PathLower, target); err != nil {
+			counter.fail(entry.PathDisplay, err)
+			return
+		}
+		counter.add("downloaded")
+	})
+
+	result := counter.result()
+	result["path"] = args.Path
+	result["local_path"] = localRoot
+	return result, nil
+}
+
+// displayRoot resolves an ID or namespace-relative folder path to its display
+// path so that entry paths can be related back to it.
+func displayRoot(client *dropbox.Client, path string) (string, error) {
+	if dropbox.IsDisplayPath(path) {
+		return path, nil
+	}
+
+	metadata, err := client.GetMetadata(path)
+	if err != nil {
+		return "", err
+	}
+	folder, ok := metadata.(*files.FolderMetadata)
+	if !ok {
+		return "", fmt.Errorf("%s is not a folder", path)
+	}
+	return folder.PathDisplay, nil
+}
+
+// downloadToFile downloads remotePath into target, creating its directory as
+// needed. Interrupted downloads are resumed by the next call.
+func downloadToFile(client *dropbox.Client, remotePath, target string) (*files.FileMetadata, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return client.DownloadToFile(remotePath, target)
+}
+
+func (h *Handler) HandleSyncUp(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		LocalPath   string `json:"local_path"`
+		Path        string `json:"path"`
+		DeleteExtra bool   `json:"delete_extra"`
+		Concurrency int    `json:"concurrency"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.LocalPath == "" {
+		return nil, fmt.Errorf("local_path parameter is required")
+	}
+
+	localRoot, err := filepath.Abs(args.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local_path: %w", err)
+	}
+
+	var localFiles, localDirs []string
+	walkErr := filepath.WalkDir(localRoot, func(name string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localRoot, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			localDirs = append(localDirs, filepath.ToSlash(rel))
+		case d.Type().IsRegular():
+			localFiles = append(localFiles, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("failed to read local directory: %w", walkErr)
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing remote folder is created along with its contents
+	remoteRoot, err := displayRoot(client, args.Path)
+	if err != nil {
+		if !dropbox.IsNotFound(err) {
+			return nil, err
+		}
+		remoteRoot = args.Path
+	}
+	remoteRoot = strings.TrimSuffix(remoteRoot, "/")
+
+	// A missing remote folder is treated as empty
+	remoteFiles := map[string]*files.FileMetadata{}
+	remoteDirs := map[string]bool{}
+	entries, err := client.ListFolderRecursive(remoteRoot)
+	if err != nil && !dropbox.IsNotFound(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case *files.FileMetadata:
+			remoteFiles[e.PathLower] = e
+		case *files.FolderMetadata:
+			remoteDirs[e.PathLower] = true
+		}
+	}
+
+	outcomes := []string{"added", "updated", "skipped", "failed"}
+	if args.DeleteExtra {
+		outcomes = append(outcomes, "deleted")
+	}
+	counter := newSyncCounter(outcomes...)
+
+	// Create missing folders up front so empty local folders are mirrored too
+	for _, dir := range localDirs {
+		remotePath := remoteRoot + "/" + dir
+		if remoteDirs[strings.ToLower(remotePath)] {
+			continue
+		}
+		if _, err := client.CreateFolder(remotePath); err != nil {
+			counter.fail(remotePath, err)
+		}
+	}
+
+	runBounded(len(localFiles), syncConcurrency(args.Concurrency), func(i int) {
+		rel := localFiles[i]
+		localPath := filepath.Join(localRoot, filepath.FromSlash(rel))
+		remotePath := remoteRoot + "/" + rel
+
+		outcome := "added"
+		if remote, exists := remoteFiles[strings.ToLower(remotePath)]; exists {
+			hash, err := dropbox.FileContentHash(localPath)
+			if err != nil {
+				counter.fail(remotePath, err)
+				return
+			}
+			if hash == remote.ContentHash {
+				counter.add("skipped")
+				return
+			}
+			outcome = "updated"
+		}
+
+		if err := uploadFromFile(client, localPath, remotePath); err != nil {
+			counter.fail(remotePath, err)
+			return
+		}
+		counter.add(outcome)
+	})
+
+	if args.DeleteExtra {
+		local := make(map[string]bool, len(localFiles))
+		for _, rel := range localFiles {
+			local[strings.ToLower(remoteRoot+"/"+rel)] = true
+		}
+
+		for pathLower, remote := range remoteFiles {
+			if local[pathLower] {
+				continue
+			}
+			if err := client.Delete(remote.PathLower); err != nil {
+				counter.fail(remote.PathDisplay, err)
+				continue
+			}
+			counter.add("deleted")
+		}
+	}
+
+	result := counter.result()
+	result["path"] = args.Path
+	result["local_path"] = localRoot
+	return result, nil
+}
+
+func uploadFromFile(client *dropbox.Client, localPath, remotePath string) error {
+	f, err := os.Open(localPath) // #nosec G304 - path comes from walking the requested directory
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	modified := info.ModTime()
+	_, err = client.UploadStream(remotePath, f, info.Size(), dropbox.UploadOptions{
+		Mode:           "overwrite",
+		ClientModified: &modified,
+	})
+	return err
+}
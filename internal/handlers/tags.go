@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// HandleFindByTag lists the files below a folder that carry a tag. Dropbox
+// search cannot filter by tag, so the folder is listed recursively and the
+// tags of its files are looked up in batches, several batches at a time.
+// Batches that fail are reported without failing the whole call.
+func (h *Handler) HandleFindByTag(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path        string `json:"path"`
+		TagText     string `json:"tag_text"`
+		Concurrency int    `json:"concurrency"`
+		HumanSizes  bool   `json:"human_sizes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	// Tags are shown with a leading # in the Dropbox UI but stored without
+	tagText := strings.TrimPrefix(args.TagText, "#")
+	if tagText == "" {
+		return nil, fmt.Errorf("tag_text parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := client.ListFolderRecursive(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileEntries []*files.FileMetadata
+	for _, entry := range entries {
+		if file, ok := entry.(*files.FileMetadata); ok {
+			fileEntries = append(fileEntries, file)
+		}
+	}
+
+	var batches [][]*files.FileMetadata
+	for start := 0; start < len(fileEntries); start += dropbox.MaxTagsPaths {
+		batches = append(batches, fileEntries[start:min(start+dropbox.MaxTagsPaths, len(fileEntries))])
+	}
+
+	var mu sync.Mutex
+	matches := []*files.FileMetadata{}
+	var failures []syncFailure
+	runBounded(len(batches), syncConcurrency(args.Concurrency), func(i int) {
+		paths := make([]string, len(batches[i]))
+		for j, file := range batches[i] {
+			paths[j] = file.PathLower
+		}
+
+		tags, err := client.GetTags(paths)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			for _, path := range paths {
+				failures = append(failures, syncFailure{Path: path, Error: err.Error()})
+			}
+			return
+		}
+		for _, file := range batches[i] {
+			if hasTag(tags[file.PathLower], tagText) {
+				matches = append(matches, file)
+			}
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].PathLower < matches[j].PathLower })
+
+	items := make([]map[string]interface{}, 0, len(matches))
+	for _, file := range matches {
+		items = append(items, map[string]interface{}{
+			"name":     file.Name,
+			"path":     file.PathDisplay,
+			"type":     typeFile,
+			"size":     file.Size,
+			"modified": file.ServerModified,
+		})
+	}
+	if args.HumanSizes || h.config.HumanSizes {
+		addHumanSizes(items)
+	}
+
+	result := map[string]interface{}{
+		"path":     args.Path,
+		"tag_text": tagText,
+		"files":    items,
+		"count":    len(items),
+		"scanned":  len(fileEntries),
+	}
+	if len(failures) > 0 {
+		result["failures"] = failures
+	}
+	return result, nil
+}
+
+// hasTag reports whether tags contain tagText. Dropbox stores tags in lower
+// case, so the comparison ignores case.
+func hasTag(tags []string, tagText string) bool {
+	for _, tag := range tags {
+		if strings.EqualFold(tag, tagText) {
+			return true
+		}
+	}
+	return false
+}
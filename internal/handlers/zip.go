@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+)
+
+// errZipLimit stops an inline zip download that outgrows the download limit.
+var errZipLimit = errors.New("zip archive exceeds the download limit")
+
+// limitedBuffer is a buffer that refuses to grow beyond limit bytes. The
+// buffer is not embedded, so that io.Copy cannot bypass Write through its
+// ReadFrom and WriteString methods.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int64
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.buf.Len()+len(p)) > b.limit {
+		return 0, errZipLimit
+	}
+	return b.buf.Write(p)
+}
+
+// HandleDownloadZip downloads a folder as a zip archive, either inline as
+// base64 or saved to local_path.
+func (h *Handler) HandleDownloadZip(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Path      string `json:"path"`
+		LocalPath string `json:"local_path"`
+		MaxBytes  int64  `json:"max_bytes"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	if args.Path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	client, err := h.newClient(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Saving to disk streams the archive, so no size limit applies
+	if args.LocalPath != "" {
+		target, err := filepath.Abs(args.LocalPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local_path: %w", err)
+		}
+		return downloadZipToFile(client, args.Path, target, h.config.TempDirectory())
+	}
+
+	limit := h.config.DownloadLimit()
+	if args.MaxBytes > 0 {
+		limit = args.MaxBytes
+	}
+
+	buf := &limitedBuffer{limit: limit}
+	folder, err := client.DownloadZip(args.Path, buf)
+	if errors.Is(err, errZipLimit) {
+		return nil, fmt.Errorf("the zip archive of %s exceeds the %d byte download limit; "+
+			"pass local_path to save it to disk, or raise max_bytes to return it inline", args.Path, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"path":      zipFolderPath(folder, args.Path),
+		"size":      buf.buf.Len(),
+		"content":   base64.StdEncoding.EncodeToString(buf.buf.Bytes()),
+		"type":      "base64",
+		"mime_type": "application/zip",
+	}, nil
+}
+
+// downloadZipToFile saves the zip archive of a folder to target. The archive
+// is written to a partial file in the temp directory first, so that a failed
+// download never leaves a truncated archive at target.
+func downloadZipToFile(client *dropbox.Client, remotePath, target, tempDir string) (interface{}, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(tempDir, "dropbox-zip-*.partial")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	partial := f.Name()
+
+	folder, err := client.DownloadZip(remotePath, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+
+	info, err := os.Stat(partial)
+	if err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := dropbox.MoveFile(partial, target); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	return map[string]interface{}{
+		"path":       zipFolderPath(folder, remotePath),
+		"local_path": target,
+		"size":       info.Size(),
+	}, nil
+}
+
+// zipFolderPath returns the display path of a zipped folder, falling back to
+// the requested path when Dropbox returned no metadata.
+func zipFolderPath(folder *files.FolderMetadata, path string) string {
+	if folder == nil {
+		return path
+	}
+	return folder.PathDisplay
+}
@@ -1,23 +1,24 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"go.ngs.io/dropbox-mcp-server/internal/config"
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
 	"go.ngs.io/dropbox-mcp-server/internal/handlers"
 )
 
-var (
-	version = "dev"
-	commit  = "none"
-	date    = "unknown"
-)
-
 type Request struct {
 	JSONRPC string          `json:"jsonrpc"`
 	ID      json.RawMessage `json:"id"`
@@ -46,8 +47,15 @@ type ToolDefinition struct {
 func main() {
 	var (
 		versionFlag = flag.Bool("version", false, "Print version information")
+		checkFlag   = flag.Bool("check", false, "Verify the configuration and credentials, then exit")
+		toolsJSON   = flag.Bool("tools-json", false, "Print the tool definitions as JSON, then exit")
 		helpFlag    = flag.Bool("h", false, "Print help message")
 		help2Flag   = flag.Bool("help", false, "Print help message")
+
+		transport       = flag.String("transport", "stdio", "Transport to serve on: stdio or http")
+		httpAddr        = flag.String("http-addr", "localhost:8080", "Address to listen on for the http transport")
+		allowedOrigins  = flag.String("allowed-origins", "", "Comma-separated browser origins the http transport accepts besides loopback")
+		shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "Time to wait for an in-flight request on SIGINT/SIGTERM")
 	)
 	flag.Parse()
 
@@ -56,6 +64,8 @@ func main() {
 		os.Exit(0)
 	}
 
+	dropbox.DefaultUserAgent = "dropbox-mcp-server/" + version
+
 	if *helpFlag || *help2Flag {
 		fmt.Println("dropbox-mcp-server - MCP server for Dropbox integration")
 		fmt.Println("\nUsage:")
@@ -63,93 +73,328 @@ func main() {
 		fmt.Println("\nOptions:")
 		fmt.Println("  -h, --help     Show this help message")
 		fmt.Println("  --version      Show version information")
+		fmt.Println("  --check        Verify the configuration and credentials, then exit")
+		fmt.Println("  --tools-json   Print the tool definitions as JSON, then exit")
+		fmt.Println("  --transport string")
+		fmt.Println("                 Transport to serve on: stdio or http (default \"stdio\")")
+		fmt.Println("  --http-addr string")
+		fmt.Println("                 Address to listen on for the http transport (default \"localhost:8080\")")
+		fmt.Println("  --allowed-origins string")
+		fmt.Println("                 Comma-separated browser origins the http transport accepts besides loopback")
+		fmt.Println("  --shutdown-timeout duration")
+		fmt.Println("                 Time to wait for an in-flight request on shutdown (default 30s)")
 		fmt.Println("\nThis tool is designed to be used with Claude Desktop.")
 		fmt.Println("See https://github.com/ngs/dropbox-mcp-server for more information.")
 		os.Exit(0)
 	}
 
+	if *checkFlag {
+		if !runCheck(os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *toolsJSON {
+		if err := writeToolsJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list tools: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	handler, err := handlers.NewHandler()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize handler: %v\n", err)
 		os.Exit(1)
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	warnUnknownTools(handler)
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	done := make(chan error, 1)
+	switch *transport {
+	case "stdio":
+		go func() {
+			done <- serveStdio(ctx, handler, os.Stdin, os.Stdout)
+		}()
+	case "http":
+		go func() {
+			done <- serveHTTP(ctx, handler, *httpAddr, splitOrigins(*allowedOrigins), *shutdownTimeout)
+		}()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown transport: %s\n", *transport)
+		os.Exit(1)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Transport error: %v\n", err)
+		}
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr, "Shutting down, waiting for in-flight request to finish...")
+		select {
+		case <-done:
+		case <-time.After(*shutdownTimeout):
+			fmt.Fprintln(os.Stderr, "Shutdown timeout exceeded, exiting")
+			os.Exit(1)
+		}
+	}
+}
+
+// handleMessage processes a single line from the transport, which is either
+// one request object or a batch (array) of requests, and returns the encoded
+// response. It returns nil when nothing should be sent back.
+func handleMessage(handler *handlers.Handler, line []byte, notify func([]byte)) []byte {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	if trimmed[0] != '[' {
 		var req Request
-		if err := json.Unmarshal(line, &req); err != nil {
+		if err := json.Unmarshal(trimmed, &req); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to parse request: %v\n", err)
-			continue
+			return nil
 		}
 
-		// Skip notifications (requests without ID)
-		if req.ID == nil {
-			// Notifications don't require a response
-			if strings.HasPrefix(req.Method, "notifications/") {
-				continue
-			}
+		resp := handleRequest(handler, &req, notify)
+		if resp == nil {
+			return nil
 		}
+		return marshalResponse(resp)
+	}
 
-		var resp Response
-		resp.JSONRPC = "2.0"
-		resp.ID = req.ID
-
-		switch req.Method {
-		case "initialize":
-			resp.Result = handleInitialize()
-		case "tools/list":
-			resp.Result = handleListTools()
-		case "tools/call":
-			resp.Result = handleToolCall(handler, req.Params)
-		case "prompts/list":
-			resp.Result = handleListPrompts()
-		case "resources/list":
-			resp.Result = handleListResources()
-		default:
-			// Only send error response for non-notification methods
-			if !strings.HasPrefix(req.Method, "notifications/") {
-				resp.Error = &Error{
-					Code:    -32601,
-					Message: fmt.Sprintf("Method not found: %s", req.Method),
-				}
-			} else {
-				continue // Skip sending response for notifications
-			}
-		}
+	var batch []Request
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse batch request: %v\n", err)
+		return nil
+	}
 
-		output, err := json.Marshal(resp)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to marshal response: %v\n", err)
-			continue
+	if len(batch) == 0 {
+		return marshalResponse(&Response{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("null"),
+			Error: &Error{
+				Code:    -32600,
+				Message: "Invalid Request: empty batch",
+			},
+		})
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for i := range batch {
+		if resp := handleRequest(handler, &batch[i], notify); resp != nil {
+			responses = append(responses, resp)
 		}
+	}
 
-		fmt.Println(string(output))
+	// A batch made up entirely of notifications gets no response
+	if len(responses) == 0 {
+		return nil
 	}
+	return marshalResponse(responses)
+}
 
-	if err := scanner.Err(); err != nil && err != io.EOF {
-		fmt.Fprintf(os.Stderr, "Scanner error: %v\n", err)
+func marshalResponse(v interface{}) []byte {
+	output, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to marshal response: %v\n", err)
+		return nil
 	}
+	return output
+}
+
+// handleRequest dispatches a single request and returns its response, or nil
+// for notifications which must not be answered.
+func handleRequest(handler *handlers.Handler, req *Request, notify func([]byte)) *Response {
+	// Notifications don't require a response
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil
+	}
+
+	resp := &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+	}
+
+	switch req.Method {
+	case "ping":
+		resp.Result = map[string]interface{}{}
+	case "initialize":
+		resp.Result = handleInitialize()
+	case "tools/list":
+		resp.Result = handleListTools(handler)
+	case "tools/call":
+		resp.Result, resp.Error = handleToolCall(handler, req.Params, notify)
+	case "prompts/list":
+		resp.Result = handleListPrompts()
+	case "resources/list":
+		resp.Result = handleListResources()
+	case "resources/templates/list":
+		resp.Result = handleListResourceTemplates(handler)
+	case "resources/read":
+		resp.Result, resp.Error = handleReadResource(handler, req.Params)
+	default:
+		resp.Error = &Error{
+			Code:    -32601,
+			Message: fmt.Sprintf("Method not found: %s", req.Method),
+		}
+	}
+
+	return resp
 }
 
 func handleInitialize() interface{} {
 	return map[string]interface{}{
 		"protocolVersion": "2024-11-05",
 		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "dropbox-mcp-server",
-			"version": VERSION,
+			"version": version,
+		},
+	}
+}
+
+// debugProperty is accepted by every tool and handled in handleToolCall.
+var debugProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "On failure, include the raw Dropbox API response and its X-Dropbox-Request-Id in the error",
+	"default":     false,
+}
+
+// withDebugProperty adds the debug argument to the schema of tool.
+func withDebugProperty(tool ToolDefinition) ToolDefinition {
+	properties, ok := tool.InputSchema["properties"].(map[string]interface{})
+	if !ok {
+		properties = map[string]interface{}{}
+		tool.InputSchema["properties"] = properties
+	}
+	properties["debug"] = debugProperty
+	return tool
+}
+
+// humanSizesProperty is shared by the tools that return file sizes.
+var humanSizesProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Add a human-readable size_human field (e.g. \"1.4 MB\") next to sizes",
+	"default":     false,
+}
+
+// modifiedAfterProperty, modifiedBeforeProperty and includeFoldersProperty
+// are shared by the tools that filter by modification time.
+var (
+	modifiedAfterProperty = map[string]interface{}{
+		"type":        "string",
+		"description": "Only return files modified after this time (RFC 3339)",
+	}
+	modifiedBeforeProperty = map[string]interface{}{
+		"type":        "string",
+		"description": "Only return files modified before this time (RFC 3339)",
+	}
+	includeFoldersProperty = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Keep folders, which have no modification time, when filtering by modified_after or modified_before",
+		"default":     false,
+	}
+)
+
+// relocationEntriesProperty is shared by the batch move and copy tools.
+var relocationEntriesProperty = map[string]interface{}{
+	"type":        "array",
+	"description": "Items to relocate",
+	"items": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"from_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Source path",
+			},
+			"to_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Destination path",
+			},
 		},
+		"required": []string{"from_path", "to_path"},
+	},
+}
+
+var batchAutorenameProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Rename items whose destination already exists instead of failing them",
+	"default":     false,
+}
+
+// batchAsyncProperty is shared by the batch tools.
+var batchAsyncProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Return the async_job_id immediately instead of waiting for the job to finish. Recommended for large batches; poll with dropbox_check_job",
+	"default":     false,
+}
+
+// batchAllowSharedFolderProperty is shared by the batch move tools.
+var batchAllowSharedFolderProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Allow moving shared folders. Without it, the batch fails before anything is moved if an entry is a shared folder",
+	"default":     false,
+}
+
+// handleListTools lists the tools enabled by configuration, hiding those that
+// need a scope the token was not granted.
+func handleListTools(handler *handlers.Handler) interface{} {
+	return map[string]interface{}{
+		"tools": availableTools(handler),
+	}
+}
+
+// availableTools returns the definitions of the tools listed by tools/list.
+func availableTools(handler *handlers.Handler) []ToolDefinition {
+	tools := []ToolDefinition{}
+	for _, tool := range toolDefinitions() {
+		if toolAvailable(handler, tool.Name) {
+			tools = append(tools, withDebugProperty(tool))
+		}
+	}
+	return tools
+}
+
+// writeToolsJSON writes the tools that tools/list would return as indented
+// JSON. It reads the configuration, so that tool filtering applies, but never
+// contacts Dropbox.
+func writeToolsJSON(w io.Writer) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(availableTools(handlers.NewHandlerFromConfig(cfg)))
+}
+
+// warnUnknownTools reports tool names in the enabled or disabled tool
+// configuration that do not match any tool.
+func warnUnknownTools(handler *handlers.Handler) {
+	known := make(map[string]bool)
+	for _, tool := range toolDefinitions() {
+		known[tool.Name] = true
+	}
+
+	for _, name := range handler.ConfiguredToolNames() {
+		if !known[name] {
+			fmt.Fprintf(os.Stderr, "Warning: unknown tool %q in tool configuration\n", name)
+		}
 	}
 }
 
-func handleListTools() interface{} {
-	tools := []ToolDefinition{
+func toolDefinitions() []ToolDefinition {
+	return []ToolDefinition{
 		{
 			Name:        "dropbox_auth",
 			Description: "Authenticate with Dropbox using OAuth 2.0",
@@ -158,81 +403,717 @@ func handleListTools() interface{} {
 				"properties": map[string]interface{}{
 					"client_id": map[string]interface{}{
 						"type":        "string",
-						"description": "Dropbox App Client ID (optional if DROPBOX_CLIENT_ID env var is set)",
+						"description": "Dropbox App Client ID (optional if DROPBOX_CLIENT_ID env var is set)",
+					},
+					"client_secret": map[string]interface{}{
+						"type":        "string",
+						"description": "Dropbox App Client Secret (optional if DROPBOX_CLIENT_SECRET env var is set)",
+					},
+					"redirect_uri": map[string]interface{}{
+						"type":        "string",
+						"description": "Redirect URI registered for the app, such as http://localhost:53682/callback. The local callback server listens on its port. Defaults to DROPBOX_REDIRECT_URI or a random port",
+					},
+					"redirect_host": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"localhost", "127.0.0.1"},
+						"description": "Host of the redirect URI on a random port, which must match the redirect URI registered for the app. Defaults to DROPBOX_REDIRECT_HOST or localhost",
+					},
+				},
+			},
+		},
+		{
+			Name:        "dropbox_exchange_code",
+			Description: "Authenticate with an authorization code obtained out of band, for scripted setups, without opening a browser. Saves the tokens like dropbox_auth",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"code": map[string]interface{}{
+						"type":        "string",
+						"description": "Authorization code returned by Dropbox",
+					},
+					"redirect_uri": map[string]interface{}{
+						"type":        "string",
+						"description": "Redirect URI the code was requested with. Omit it if the code was requested without one",
+					},
+					"client_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Dropbox App Client ID (optional if DROPBOX_CLIENT_ID env var is set)",
+					},
+					"client_secret": map[string]interface{}{
+						"type":        "string",
+						"description": "Dropbox App Client Secret (optional if DROPBOX_CLIENT_SECRET env var is set)",
+					},
+				},
+				"required": []string{"code"},
+			},
+		},
+		{
+			Name:        "dropbox_check_auth",
+			Description: "Check current authentication status and the scopes granted to the token, or \"unknown\" when Dropbox did not report them",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "dropbox_status",
+			Description: "Show authentication status, the current account, space usage and token expiry in one call",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "dropbox_version",
+			Description: "Get the version of this server",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"verbose": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the commit and date of the build",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "dropbox_ping",
+			Description: "Check that the server is alive and the stored token is still accepted by Dropbox",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "dropbox_set_team_member",
+			Description: "Set the Dropbox Business team member that file operations run as (requires a team-scoped token)",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"member_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Team member ID (e.g. dbmid:...); empty string to clear",
+					},
+				},
+				"required": []string{"member_id"},
+			},
+		},
+		{
+			Name:        "dropbox_set_path_root",
+			Description: "Set the namespace that paths are resolved against, e.g. the team space of a Dropbox Business account",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path_root": map[string]interface{}{
+						"type":        "string",
+						"description": "'home' for the member's own folder, 'root' for the team space, a numeric namespace ID, or empty string for the default",
+					},
+				},
+				"required": []string{"path_root"},
+			},
+		},
+		{
+			Name:        "dropbox_list_team_members",
+			Description: "List members of the Dropbox Business team (requires a team-scoped token)",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"include_removed": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include members that have been removed from the team",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "dropbox_list",
+			Description: "List files and folders in a Dropbox directory",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to list (empty string for root)",
+						"default":     "",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include the contents of all subfolders",
+						"default":     false,
+					},
+					"output": map[string]interface{}{
+						"type":        "string",
+						"description": "Output format: 'json' for a list of entries, 'tree' for an indented text tree",
+						"default":     "json",
+						"enum":        []string{"json", "tree"},
+					},
+					"incremental": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return only what changed since the previous incremental listing of this folder, including deleted entries. The first call returns the full listing",
+						"default":     false,
+					},
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "Only return entries whose name matches this glob pattern, such as '*.pdf' or 'report-*'. Combined with recursive, this finds matching items anywhere below the folder",
+					},
+					"ignore_case": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match pattern case-insensitively",
+						"default":     false,
+					},
+					"modified_after":  modifiedAfterProperty,
+					"modified_before": modifiedBeforeProperty,
+					"include_folders": includeFoldersProperty,
+					"summary": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return the entries together with a summary of the number of files and folders and the total size of the files listed",
+						"default":     false,
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Return one page of about this many entries (at most 2000), before filtering. When has_more is true, pass next_cursor and the same filters to dropbox_list_continue for the next page",
+						"minimum":     1,
+						"maximum":     2000,
+					},
+					"human_sizes": humanSizesProperty,
+				},
+			},
+		},
+		{
+			Name:        "dropbox_list_continue",
+			Description: "Get the next page of a listing started with dropbox_list and a limit, using the next_cursor it returned. Repeat while has_more is true",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "next_cursor returned by dropbox_list or a previous dropbox_list_continue",
+					},
+					"summary": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Add a summary of the number of files and folders and the total size of the files on this page",
+						"default":     false,
+					},
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "Only return entries whose name matches this glob pattern. Pass the same filters as the dropbox_list call, as the cursor does not keep them",
+					},
+					"ignore_case": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match pattern case-insensitively",
+						"default":     false,
+					},
+					"modified_after":  modifiedAfterProperty,
+					"modified_before": modifiedBeforeProperty,
+					"include_folders": includeFoldersProperty,
+					"human_sizes":     humanSizesProperty,
+				},
+				"required": []string{"cursor"},
+			},
+		},
+		{
+			Name:        "dropbox_folder_size",
+			Description: "Get the number of files and folders below a Dropbox folder and their total size, without listing them",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the folder (empty string for root)",
+						"default":     "",
+					},
+					"subfolders": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also return the totals of each immediate subfolder, largest first",
+						"default":     false,
+					},
+					"human_sizes": humanSizesProperty,
+				},
+			},
+		},
+		{
+			Name:        "dropbox_list_deleted",
+			Description: "List deleted files and folders in a Dropbox folder. To recover a file, find its revisions with dropbox_get_revisions and restore one with dropbox_restore_file",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the folder (empty string for root)",
+						"default":     "",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include deletions anywhere below the folder",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "dropbox_get_latest_cursor",
+			Description: "Get a cursor for the current state of a folder without listing it, as the starting point for watching it for changes",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the folder (empty string for root)",
+						"default":     "",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include changes in subfolders",
+						"default":     false,
+					},
+					"include_deleted": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include deleted entries in later changes",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "dropbox_watch",
+			Description: "Wait for changes below a Dropbox folder and return the changed entries, including deleted ones, with a cursor. Call it again with that cursor to keep watching; a call that times out returns no changes",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Folder to watch (empty string for root)",
+						"default":     "",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also watch all subfolders",
+						"default":     false,
+					},
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "Cursor returned by a previous dropbox_watch or dropbox_get_latest_cursor. Without it, watching starts from the current state of the folder",
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seconds to wait for changes, between 30 and 480. Dropbox may add up to 90 seconds",
+						"default":     30,
+						"minimum":     30,
+						"maximum":     480,
+					},
+					"human_sizes": humanSizesProperty,
+				},
+			},
+		},
+		{
+			Name:        "dropbox_search",
+			Description: "Search for files and folders in Dropbox. Returns up to 100 matches; when has_more is true, pass the returned next_cursor to dropbox_search_continue for the next page",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Search query",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to search in (optional)",
+					},
+					"order_by": map[string]interface{}{
+						"type":        "string",
+						"description": "Order of the matches: 'relevance', or 'last_modified_time' for the most recently modified first",
+						"default":     "relevance",
+						"enum":        []string{"relevance", "last_modified_time"},
+					},
+					"modified_after":  modifiedAfterProperty,
+					"modified_before": modifiedBeforeProperty,
+					"include_folders": includeFoldersProperty,
+					"human_sizes":     humanSizesProperty,
+				},
+				"required": []string{"query"},
+			},
+		},
+		{
+			Name:        "dropbox_search_continue",
+			Description: "Get the next page of a search started with dropbox_search, using the next_cursor it returned. Repeat while has_more is true",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "next_cursor returned by dropbox_search or a previous dropbox_search_continue",
+					},
+					"modified_after":  modifiedAfterProperty,
+					"modified_before": modifiedBeforeProperty,
+					"include_folders": includeFoldersProperty,
+					"human_sizes":     humanSizesProperty,
+				},
+				"required": []string{"cursor"},
+			},
+		},
+		{
+			Name: "dropbox_find_by_tag",
+			Description: "Find the files below a folder that carry a tag. The folder is listed recursively and tags are " +
+				"looked up for every file, so large trees take many API calls",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Folder to search (empty string for root)",
+						"default":     "",
+					},
+					"tag_text": map[string]interface{}{
+						"type":        "string",
+						"description": "Tag to look for, with or without a leading #",
+					},
+					"concurrency": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of parallel tag lookups, each covering up to 100 files (1-8)",
+						"default":     4,
+					},
+					"human_sizes": humanSizesProperty,
+				},
+				"required": []string{"tag_text"},
+			},
+		},
+		{
+			Name:        "dropbox_get_metadata",
+			Description: "Get metadata for a file or folder. Files report locked, and for a locked file the lock holder and when the lock was taken",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file or folder",
+					},
+					"include_link": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include a temporary download link for files (valid for 4 hours)",
+						"default":     false,
+					},
+					"verbose": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include all available metadata: media info, sharing info, property groups, symlink and lock info",
+						"default":     false,
+					},
+					"human_sizes": humanSizesProperty,
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dropbox_exists",
+			Description: "Check whether a file or folder exists. Returns exists: false instead of an error when the path is missing",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to check",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dropbox_compare",
+			Description: "Check whether a local file has the same content as a Dropbox file by comparing content hashes. Returns match, a status of match, differ, local_missing, remote_missing or both_missing, and the hash and size of each side",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"local_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Local file to compare",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Dropbox file to compare against",
+					},
+				},
+				"required": []string{"local_path", "path"},
+			},
+		},
+		{
+			Name:        "dropbox_resolve_path",
+			Description: "Resolve a file ID (id:...), namespace-relative path (ns:123/...) or revision (rev:...) to its current display path",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "ID or path to resolve",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dropbox_check_locks",
+			Description: "Check whether files are locked, and by whom, without locking them",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"paths": map[string]interface{}{
+						"type":        "array",
+						"description": "Paths of the files to check",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+				"required": []string{"paths"},
+			},
+		},
+		{
+			Name:        "dropbox_download",
+			Description: "Download a file from Dropbox",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file to download",
+					},
+					"local_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Save the file to this local path instead of returning its content. Use this for large files",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Override the configured size limit for content returned inline (default 25MB)",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Byte offset to start reading at. With offset or length only that part of the file is returned, along with truncated and next_offset for reading the next page",
+						"default":     0,
+					},
+					"length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of bytes to return from offset (default and cap: the inline size limit)",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dropbox_download_zip",
+			Description: "Download a folder from Dropbox as a zip archive, which is much faster than downloading its files one by one. Dropbox zips folders of up to 20 GB and 10,000 files",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the folder to download",
+					},
+					"local_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Save the zip archive to this local path instead of returning it base64-encoded",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Override the configured size limit for archives returned inline (default 25MB)",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dropbox_read_range",
+			Description: "Read a byte range of a file, returned as base64, without downloading the whole file. Useful for inspecting headers or extracting segments of large binary files",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Byte offset to start reading at",
+						"default":     0,
+					},
+					"length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of bytes to read, up to the inline size limit. Fewer bytes are returned at the end of the file",
+					},
+				},
+				"required": []string{"path", "length"},
+			},
+		},
+		{
+			Name:        "dropbox_download_shared_link",
+			Description: "Download a file through a shared link, including links to content in someone else's Dropbox",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "Shared link URL",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "For folder links, path of the file relative to the shared folder (e.g. /report.pdf)",
+					},
+					"link_password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password for password-protected links",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Override the configured size limit for content returned inline (default 25MB)",
+					},
+				},
+				"required": []string{"url"},
+			},
+		},
+		{
+			Name:        "dropbox_export",
+			Description: "Export a file that cannot be downloaded directly, such as a Paper doc, to a downloadable format",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file to export",
 					},
-					"client_secret": map[string]interface{}{
+					"format": map[string]interface{}{
 						"type":        "string",
-						"description": "Dropbox App Client Secret (optional if DROPBOX_CLIENT_SECRET env var is set)",
+						"description": "Export format, one of the file's export options (optional, defaults to the file's export format)",
 					},
 				},
+				"required": []string{"path"},
 			},
 		},
 		{
-			Name:        "dropbox_check_auth",
-			Description: "Check current authentication status",
+			Name: "dropbox_list_paper_docs",
+			Description: "List the IDs of Paper docs the user can access. Only covers docs from the Paper API; " +
+				"docs stored as .paper files are found with dropbox_search",
 			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
+				"type": "object",
+				"properties": map[string]interface{}{
+					"filter_by": map[string]interface{}{
+						"type":        "string",
+						"description": "Which docs to list: those the user accessed or those they created",
+						"enum":        []string{"docs_accessed", "docs_created"},
+						"default":     "docs_accessed",
+					},
+					"sort_by": map[string]interface{}{
+						"type":        "string",
+						"description": "Time to sort the docs by",
+						"enum":        []string{"accessed", "modified", "created"},
+						"default":     "accessed",
+					},
+					"sort_order": map[string]interface{}{
+						"type":    "string",
+						"enum":    []string{"ascending", "descending"},
+						"default": "ascending",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of doc IDs to return",
+						"default":     100,
+						"minimum":     1,
+					},
+				},
 			},
 		},
 		{
-			Name:        "dropbox_list",
-			Description: "List files and folders in a Dropbox directory",
+			Name:        "dropbox_create_paper_doc",
+			Description: "Create a Paper doc from markdown, HTML or plain text. Paper takes the title from the first line",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"path": map[string]interface{}{
+					"content": map[string]interface{}{
 						"type":        "string",
-						"description": "Path to list (empty string for root)",
-						"default":     "",
+						"description": "Content of the doc",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"description": "Format of content",
+						"enum":        []string{"markdown", "html", "plain_text"},
+						"default":     "markdown",
+					},
+					"parent_folder_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Paper folder to create the doc in (optional, defaults to the top level)",
 					},
 				},
+				"required": []string{"content"},
 			},
 		},
 		{
-			Name:        "dropbox_search",
-			Description: "Search for files and folders in Dropbox",
+			Name:        "dropbox_download_paper_doc",
+			Description: "Download the content of a Paper doc as markdown or HTML",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
+					"doc_id": map[string]interface{}{
 						"type":        "string",
-						"description": "Search query",
+						"description": "ID of the doc, as returned by dropbox_list_paper_docs",
 					},
-					"path": map[string]interface{}{
-						"type":        "string",
-						"description": "Path to search in (optional)",
+					"format": map[string]interface{}{
+						"type":    "string",
+						"enum":    []string{"markdown", "html"},
+						"default": "markdown",
 					},
 				},
-				"required": []string{"query"},
+				"required": []string{"doc_id"},
 			},
 		},
 		{
-			Name:        "dropbox_get_metadata",
-			Description: "Get metadata for a file or folder",
+			Name:        "dropbox_sync_down",
+			Description: "Mirror a Dropbox folder into a local directory, skipping files that are already up to date",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"path": map[string]interface{}{
 						"type":        "string",
-						"description": "Path to the file or folder",
+						"description": "Dropbox folder to mirror (empty string for root)",
+						"default":     "",
+					},
+					"local_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Local directory to download into",
+					},
+					"concurrency": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of parallel downloads (1-8)",
+						"default":     4,
 					},
 				},
-				"required": []string{"path"},
+				"required": []string{"local_path"},
 			},
 		},
 		{
-			Name:        "dropbox_download",
-			Description: "Download a file from Dropbox",
+			Name:        "dropbox_sync_up",
+			Description: "Upload a local directory to Dropbox, skipping files that are unchanged",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
+					"local_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Local directory to upload",
+					},
 					"path": map[string]interface{}{
 						"type":        "string",
-						"description": "Path to the file to download",
+						"description": "Dropbox folder to upload into (empty string for root)",
+						"default":     "",
+					},
+					"delete_extra": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Delete remote files that don't exist locally",
+						"default":     false,
+					},
+					"concurrency": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of parallel uploads (1-8)",
+						"default":     4,
 					},
 				},
-				"required": []string{"path"},
+				"required": []string{"local_path"},
 			},
 		},
 		{
@@ -247,16 +1128,46 @@ func handleListTools() interface{} {
 					},
 					"content": map[string]interface{}{
 						"type":        "string",
-						"description": "File content (text or base64 encoded)",
+						"description": "File content (text or base64 encoded). Either content or source_url is required",
+					},
+					"source_url": map[string]interface{}{
+						"type":        "string",
+						"description": "http or https URL to fetch the content from instead of passing it inline. The server streams it to Dropbox in chunks, so this works for large files and for URLs Dropbox itself cannot reach",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Fail a source_url upload whose content is larger than this many bytes (default: the Dropbox limit of 350 GB)",
 					},
 					"mode": map[string]interface{}{
 						"type":        "string",
-						"description": "Upload mode: 'add' or 'overwrite'",
+						"description": "Upload mode: 'add', 'overwrite', or 'update' to replace only the given rev",
 						"default":     "add",
-						"enum":        []string{"add", "overwrite"},
+						"enum":        []string{"add", "overwrite", "update"},
+					},
+					"rev": map[string]interface{}{
+						"type":        "string",
+						"description": "Revision the upload is expected to replace (required for 'update' mode). If the file has changed since, the result has conflict set to true and the upload is saved under a renamed path, or not saved at all when autorename is off",
 					},
+					"client_modified": map[string]interface{}{
+						"type":        "string",
+						"description": "Modification time to record for the file (RFC 3339, rounded to seconds by Dropbox). Defaults to now",
+					},
+					"autorename": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Save the upload under a new name such as 'notes (1).txt' when the path is taken; the result's renamed flag and path show where it was saved. When false, a conflict fails the upload. Defaults to true unless the server is configured otherwise",
+					},
+					"check_conflict": map[string]interface{}{
+						"type":        "boolean",
+						"description": "In 'add' mode without autorename, check whether the path is taken before sending the content",
+						"default":     false,
+					},
+					"content_type": map[string]interface{}{
+						"type":        "string",
+						"description": "MIME type of the content. Dropbox derives the type from the extension, so this is only checked against it; a mismatch is reported as a warning",
+					},
+					"human_sizes": humanSizesProperty,
 				},
-				"required": []string{"path", "content"},
+				"required": []string{"path"},
 			},
 		},
 		{
@@ -269,6 +1180,16 @@ func handleListTools() interface{} {
 						"type":        "string",
 						"description": "Path of the folder to create",
 					},
+					"exist_ok": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Succeed and return the existing folder if one already exists at the path",
+						"default":     false,
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Create missing parent folders as needed, like mkdir -p. Existing folders are not an error",
+						"default":     false,
+					},
 				},
 				"required": []string{"path"},
 			},
@@ -287,10 +1208,48 @@ func handleListTools() interface{} {
 						"type":        "string",
 						"description": "Destination path",
 					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace an existing file at the destination. The replaced file is deleted and can be restored from the Dropbox trash. A folder at the destination is never replaced",
+						"default":     false,
+					},
+					"allow_ownership_transfer": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow a move between namespaces, such as from a member folder into a team folder, that changes who owns the content",
+						"default":     false,
+					},
+					"allow_shared_folder": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow moving a shared folder. Without it, moving a shared folder fails so that collaborators are not disrupted by accident",
+						"default":     false,
+					},
 				},
 				"required": []string{"from_path", "to_path"},
 			},
 		},
+		{
+			Name:        "dropbox_rename",
+			Description: "Rename a file or folder in place, keeping it in the same folder. Changing only the case of the name is supported",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path of the file or folder to rename",
+					},
+					"new_name": map[string]interface{}{
+						"type":        "string",
+						"description": "New name for the item, without any folder",
+					},
+					"allow_shared_folder": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow renaming a shared folder. Without it, renaming a shared folder fails so that collaborators are not disrupted by accident",
+						"default":     false,
+					},
+				},
+				"required": []string{"path", "new_name"},
+			},
+		},
 		{
 			Name:        "dropbox_copy",
 			Description: "Copy a file or folder",
@@ -323,6 +1282,93 @@ func handleListTools() interface{} {
 				"required": []string{"path"},
 			},
 		},
+		{
+			Name:        "dropbox_move_batch",
+			Description: "Move several files or folders in one batch job. By default waits for the job to finish; with async it returns a job ID at once for dropbox_check_job",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"entries":             relocationEntriesProperty,
+					"autorename":          batchAutorenameProperty,
+					"async":               batchAsyncProperty,
+					"allow_shared_folder": batchAllowSharedFolderProperty,
+				},
+				"required": []string{"entries"},
+			},
+		},
+		{
+			Name:        "dropbox_move_batch_with_progress",
+			Description: "Move many files or folders in a series of batch jobs, sending a progress notification (e.g. \"12/50 moved\") as each job completes when the call has a progressToken. If the call runs out of time, the results so far are returned with the entries that remain",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"entries":    relocationEntriesProperty,
+					"autorename": batchAutorenameProperty,
+					"chunk_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of entries moved per batch job, i.e. between progress notifications",
+						"default":     10,
+					},
+					"allow_shared_folder": batchAllowSharedFolderProperty,
+				},
+				"required": []string{"entries"},
+			},
+		},
+		{
+			Name:        "dropbox_copy_batch",
+			Description: "Copy several files or folders in one batch job. By default waits for the job to finish; with async it returns a job ID at once for dropbox_check_job",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"entries":    relocationEntriesProperty,
+					"autorename": batchAutorenameProperty,
+					"async":      batchAsyncProperty,
+					"dedup": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Copy each distinct file content once and save further copies of identical files from a copy reference, e.g. when fanning out a template to many folders. Those copies are listed under deduplicated and are never autorenamed",
+						"default":     false,
+					},
+				},
+				"required": []string{"entries"},
+			},
+		},
+		{
+			Name:        "dropbox_delete_batch",
+			Description: "Delete several files or folders in one batch job. By default waits for the job to finish; with async it returns a job ID at once for dropbox_check_job",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"paths": map[string]interface{}{
+						"type":        "array",
+						"description": "Paths to delete",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+					"async": batchAsyncProperty,
+				},
+				"required": []string{"paths"},
+			},
+		},
+		{
+			Name:        "dropbox_check_job",
+			Description: "Check the status of an asynchronous batch move, copy or delete job",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"async_job_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Job ID returned when the batch was started",
+					},
+					"job_type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"move", "copy", "delete"},
+						"description": "Kind of batch the job belongs to",
+					},
+				},
+				"required": []string{"async_job_id", "job_type"},
+			},
+		},
 		{
 			Name:        "dropbox_create_shared_link",
 			Description: "Create a shared link for a file or folder",
@@ -361,6 +1407,11 @@ func handleListTools() interface{} {
 						"type":        "string",
 						"description": "Path to list shared links for (optional)",
 					},
+					"direct_only": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only return links created directly on the path, excluding links inherited from a shared parent folder",
+						"default":     false,
+					},
 				},
 			},
 		},
@@ -378,20 +1429,106 @@ func handleListTools() interface{} {
 				"required": []string{"url"},
 			},
 		},
+		{
+			Name:        "dropbox_get_shared_folder_metadata",
+			Description: "Get the name, path, access level and policies of a shared folder: who can be a member, who can add members, and who shared links work for. Shared folder IDs appear as shared_folder_id or parent_shared_folder_id in metadata",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"shared_folder_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the shared folder",
+					},
+				},
+				"required": []string{"shared_folder_id"},
+			},
+		},
+		{
+			Name:        "dropbox_list_mountable_folders",
+			Description: "List the shared folders the current account can mount or unmount. mounted tells whether a folder is in the account's Dropbox and path where",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "dropbox_mount_folder",
+			Description: "Mount a shared folder that was shared with the current account but is not yet in its Dropbox. Returns the path the folder was mounted at",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"shared_folder_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the shared folder to mount",
+					},
+				},
+				"required": []string{"shared_folder_id"},
+			},
+		},
+		{
+			Name:        "dropbox_unmount_folder",
+			Description: "Remove a shared folder from the current account's Dropbox. The account stays a member and can mount it again",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"shared_folder_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the shared folder to unmount",
+					},
+				},
+				"required": []string{"shared_folder_id"},
+			},
+		},
 		{
 			Name:        "dropbox_get_revisions",
-			Description: "Get version history of a file",
+			Description: "Get version history of a file, newest first, with the name, size and content_hash of each revision. is_deleted and server_deleted show whether and when the file was deleted; the newest revision of a deleted file is the one to restore",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"path": map[string]interface{}{
 						"type":        "string",
-						"description": "Path to the file",
+						"description": "Path or ID (id:...) of the file",
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"path", "id"},
+						"description": "path lists the revisions stored at the path; id follows the file across moves and renames and reports the path of each revision",
+						"default":     "path",
 					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of revisions to return (1-100)",
+						"minimum":     1,
+						"maximum":     100,
+						"default":     100,
+					},
+					"human_sizes": humanSizesProperty,
 				},
 				"required": []string{"path"},
 			},
 		},
+		{
+			Name:        "dropbox_diff_revisions",
+			Description: "Show a unified diff between two revisions of a text file",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the file",
+					},
+					"rev_a": map[string]interface{}{
+						"type":        "string",
+						"description": "Older revision ID",
+					},
+					"rev_b": map[string]interface{}{
+						"type":        "string",
+						"description": "Newer revision ID",
+					},
+				},
+				"required": []string{"path", "rev_a", "rev_b"},
+			},
+		},
 		{
 			Name:        "dropbox_restore_file",
 			Description: "Restore a file to a specific version",
@@ -411,75 +1548,333 @@ func handleListTools() interface{} {
 			},
 		},
 	}
+}
 
-	return map[string]interface{}{
-		"tools": tools,
+const (
+	defaultCallTimeout     = 60 * time.Second
+	defaultTransferTimeout = 10 * time.Minute
+)
+
+// transferTools move file content or wait for changes, and get a longer
+// default timeout than metadata calls.
+var transferTools = map[string]bool{
+	"dropbox_download":             true,
+	"dropbox_watch":                true,
+	"dropbox_download_zip":         true,
+	"dropbox_read_range":           true,
+	"dropbox_download_shared_link": true,
+	"dropbox_export":               true,
+	"dropbox_download_paper_doc":   true,
+	"dropbox_sync_down":            true,
+	"dropbox_sync_up":              true,
+	"dropbox_compare":              true,
+	"dropbox_find_by_tag":          true,
+	"dropbox_upload":               true,
+}
+
+// toolScopes maps tools to the Dropbox scope they need. Tools that are not
+// listed work with any token.
+var toolScopes = map[string]string{
+	"dropbox_list_team_members":          "members.read",
+	"dropbox_list":                       "files.metadata.read",
+	"dropbox_list_continue":              "files.metadata.read",
+	"dropbox_folder_size":                "files.metadata.read",
+	"dropbox_list_deleted":               "files.metadata.read",
+	"dropbox_get_latest_cursor":          "files.metadata.read",
+	"dropbox_watch":                      "files.metadata.read",
+	"dropbox_search":                     "files.metadata.read",
+	"dropbox_search_continue":            "files.metadata.read",
+	"dropbox_find_by_tag":                "files.metadata.read",
+	"dropbox_get_metadata":               "files.metadata.read",
+	"dropbox_exists":                     "files.metadata.read",
+	"dropbox_compare":                    "files.metadata.read",
+	"dropbox_resolve_path":               "files.metadata.read",
+	"dropbox_check_locks":                "files.metadata.read",
+	"dropbox_get_revisions":              "files.metadata.read",
+	"dropbox_download":                   "files.content.read",
+	"dropbox_download_zip":               "files.content.read",
+	"dropbox_read_range":                 "files.content.read",
+	"dropbox_export":                     "files.content.read",
+	"dropbox_list_paper_docs":            "files.metadata.read",
+	"dropbox_create_paper_doc":           "files.content.write",
+	"dropbox_download_paper_doc":         "files.content.read",
+	"dropbox_download_shared_link":       "sharing.read",
+	"dropbox_sync_down":                  "files.content.read",
+	"dropbox_diff_revisions":             "files.content.read",
+	"dropbox_upload":                     "files.content.write",
+	"dropbox_sync_up":                    "files.content.write",
+	"dropbox_create_folder":              "files.content.write",
+	"dropbox_move":                       "files.content.write",
+	"dropbox_rename":                     "files.content.write",
+	"dropbox_copy":                       "files.content.write",
+	"dropbox_delete":                     "files.content.write",
+	"dropbox_restore_file":               "files.content.write",
+	"dropbox_move_batch":                 "files.content.write",
+	"dropbox_move_batch_with_progress":   "files.content.write",
+	"dropbox_copy_batch":                 "files.content.write",
+	"dropbox_delete_batch":               "files.content.write",
+	"dropbox_check_job":                  "files.content.write",
+	"dropbox_list_shared_links":          "sharing.read",
+	"dropbox_create_shared_link":         "sharing.write",
+	"dropbox_revoke_shared_link":         "sharing.write",
+	"dropbox_get_shared_folder_metadata": "sharing.read",
+	"dropbox_list_mountable_folders":     "sharing.read",
+	"dropbox_mount_folder":               "sharing.write",
+	"dropbox_unmount_folder":             "sharing.write",
+}
+
+// toolAvailable reports whether a tool is enabled by configuration and its
+// scope, if any, was granted to the token.
+func toolAvailable(handler *handlers.Handler, name string) bool {
+	if !handler.ToolEnabled(name) {
+		return false
+	}
+	scope, ok := toolScopes[name]
+	return !ok || handler.ScopeGranted(scope)
+}
+
+// toolCallTimeout returns the timeout for a tool call, configurable with
+// DROPBOX_MCP_CALL_TIMEOUT and DROPBOX_MCP_TRANSFER_TIMEOUT. As for the HTTP
+// client settings, zero disables the timeout.
+func toolCallTimeout(name string) time.Duration {
+	if transferTools[name] {
+		return dropbox.EnvDuration("DROPBOX_MCP_TRANSFER_TIMEOUT", defaultTransferTimeout)
+	}
+	return dropbox.EnvDuration("DROPBOX_MCP_CALL_TIMEOUT", defaultCallTimeout)
+}
+
+// timeoutContext returns a context that expires after timeout, or only
+// when cancelled if timeout is zero.
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
 	}
+	return context.WithTimeout(context.Background(), timeout)
 }
 
-func handleToolCall(handler *handlers.Handler, params json.RawMessage) interface{} {
+// toolHandlerFunc executes a tool call with its raw arguments.
+type toolHandlerFunc func(context.Context, json.RawMessage) (interface{}, error)
+
+// toolHandlerFuncs maps tool names to the handler methods that execute them.
+func toolHandlerFuncs(handler *handlers.Handler) map[string]toolHandlerFunc {
+	return map[string]toolHandlerFunc{
+		"dropbox_auth":                       handler.HandleAuth,
+		"dropbox_exchange_code":              handler.HandleExchangeCode,
+		"dropbox_check_auth":                 handler.HandleCheckAuth,
+		"dropbox_status":                     handler.HandleStatus,
+		"dropbox_ping":                       handler.HandlePing,
+		"dropbox_version":                    handleVersion,
+		"dropbox_set_team_member":            handler.HandleSetTeamMember,
+		"dropbox_set_path_root":              handler.HandleSetPathRoot,
+		"dropbox_list_team_members":          handler.HandleListTeamMembers,
+		"dropbox_list":                       handler.HandleList,
+		"dropbox_list_continue":              handler.HandleListContinue,
+		"dropbox_folder_size":                handler.HandleFolderSize,
+		"dropbox_list_deleted":               handler.HandleListDeleted,
+		"dropbox_get_latest_cursor":          handler.HandleGetLatestCursor,
+		"dropbox_watch":                      handler.HandleWatch,
+		"dropbox_search":                     handler.HandleSearch,
+		"dropbox_search_continue":            handler.HandleSearchContinue,
+		"dropbox_get_metadata":               handler.HandleGetMetadata,
+		"dropbox_exists":                     handler.HandleExists,
+		"dropbox_compare":                    handler.HandleCompare,
+		"dropbox_resolve_path":               handler.HandleResolvePath,
+		"dropbox_find_by_tag":                handler.HandleFindByTag,
+		"dropbox_check_locks":                handler.HandleCheckLocks,
+		"dropbox_download":                   handler.HandleDownload,
+		"dropbox_download_zip":               handler.HandleDownloadZip,
+		"dropbox_read_range":                 handler.HandleReadRange,
+		"dropbox_download_shared_link":       handler.HandleDownloadSharedLink,
+		"dropbox_export":                     handler.HandleExport,
+		"dropbox_list_paper_docs":            handler.HandleListPaperDocs,
+		"dropbox_create_paper_doc":           handler.HandleCreatePaperDoc,
+		"dropbox_download_paper_doc":         handler.HandleDownloadPaperDoc,
+		"dropbox_sync_down":                  handler.HandleSyncDown,
+		"dropbox_sync_up":                    handler.HandleSyncUp,
+		"dropbox_upload":                     handler.HandleUpload,
+		"dropbox_create_folder":              handler.HandleCreateFolder,
+		"dropbox_move":                       handler.HandleMove,
+		"dropbox_rename":                     handler.HandleRename,
+		"dropbox_copy":                       handler.HandleCopy,
+		"dropbox_delete":                     handler.HandleDelete,
+		"dropbox_move_batch":                 handler.HandleMoveBatch,
+		"dropbox_move_batch_with_progress":   handler.HandleMoveBatchWithProgress,
+		"dropbox_copy_batch":                 handler.HandleCopyBatch,
+		"dropbox_delete_batch":               handler.HandleDeleteBatch,
+		"dropbox_check_job":                  handler.HandleCheckJob,
+		"dropbox_create_shared_link":         handler.HandleCreateSharedLink,
+		"dropbox_list_shared_links":          handler.HandleListSharedLinks,
+		"dropbox_revoke_shared_link":         handler.HandleRevokeSharedLink,
+		"dropbox_get_shared_folder_metadata": handler.HandleGetSharedFolderMetadata,
+		"dropbox_list_mountable_folders":     handler.HandleListMountableFolders,
+		"dropbox_mount_folder":               handler.HandleMountFolder,
+		"dropbox_unmount_folder":             handler.HandleUnmountFolder,
+		"dropbox_get_revisions":              handler.HandleGetRevisions,
+		"dropbox_diff_revisions":             handler.HandleDiffRevisions,
+		"dropbox_restore_file":               handler.HandleRestoreFile,
+	}
+}
+
+func handleToolCall(handler *handlers.Handler, params json.RawMessage, notify func([]byte)) (interface{}, *Error) {
 	var toolCall struct {
 		Name      string          `json:"name"`
 		Arguments json.RawMessage `json:"arguments"`
+		Meta      struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
 	}
 
 	if err := json.Unmarshal(params, &toolCall); err != nil {
-		return map[string]interface{}{
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": fmt.Sprintf("Invalid params: %v", err),
-			},
+		return nil, &Error{
+			Code:    -32602,
+			Message: fmt.Sprintf("Invalid params: %v", err),
 		}
 	}
 
-	// Map of tool names to handler functions
-	toolHandlers := map[string]func(json.RawMessage) (interface{}, error){
-		"dropbox_auth":               handler.HandleAuth,
-		"dropbox_check_auth":         handler.HandleCheckAuth,
-		"dropbox_list":               handler.HandleList,
-		"dropbox_search":             handler.HandleSearch,
-		"dropbox_get_metadata":       handler.HandleGetMetadata,
-		"dropbox_download":           handler.HandleDownload,
-		"dropbox_upload":             handler.HandleUpload,
-		"dropbox_create_folder":      handler.HandleCreateFolder,
-		"dropbox_move":               handler.HandleMove,
-		"dropbox_copy":               handler.HandleCopy,
-		"dropbox_delete":             handler.HandleDelete,
-		"dropbox_create_shared_link": handler.HandleCreateSharedLink,
-		"dropbox_list_shared_links":  handler.HandleListSharedLinks,
-		"dropbox_revoke_shared_link": handler.HandleRevokeSharedLink,
-		"dropbox_get_revisions":      handler.HandleGetRevisions,
-		"dropbox_restore_file":       handler.HandleRestoreFile,
-	}
-
-	handlerFunc, exists := toolHandlers[toolCall.Name]
-	if !exists {
-		return map[string]interface{}{
-			"error": map[string]interface{}{
-				"code":    -32601,
-				"message": fmt.Sprintf("Unknown tool: %s", toolCall.Name),
-			},
+	handlerFunc, exists := toolHandlerFuncs(handler)[toolCall.Name]
+	if !exists || !handler.ToolEnabled(toolCall.Name) {
+		return nil, &Error{
+			Code:    -32602,
+			Message: fmt.Sprintf("Unknown tool: %s", toolCall.Name),
+		}
+	}
+
+	if scope, ok := toolScopes[toolCall.Name]; ok && !handler.ScopeGranted(scope) {
+		return toolResult(fmt.Sprintf("%s requires the %s scope, which was not granted to the current token. Enable it in the Dropbox app console and run dropbox_auth again.", toolCall.Name, scope), nil, true), nil
+	}
+
+	// Tools without arguments may omit the field entirely
+	if len(toolCall.Arguments) == 0 {
+		toolCall.Arguments = json.RawMessage("{}")
+	}
+
+	if tool, ok := findTool(toolCall.Name); ok {
+		if err := validateArguments(withDebugProperty(tool), toolCall.Arguments); err != nil {
+			return nil, &Error{
+				Code:    -32602,
+				Message: fmt.Sprintf("Invalid params: %v", err),
+			}
 		}
 	}
 
-	result, err := handlerFunc(toolCall.Arguments)
+	timeout := toolCallTimeout(toolCall.Name)
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+
+	if len(toolCall.Meta.ProgressToken) > 0 && notify != nil {
+		ctx = handlers.WithProgress(ctx, progressNotifier(toolCall.Meta.ProgressToken, notify))
+	}
+	ctx, failures := dropbox.WithFailureLog(ctx)
+
+	result, err := handlerFunc(ctx, toolCall.Arguments)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("tool call %s timed out after %s: %w", toolCall.Name, timeout, err)
+	}
 
+	// Tool execution failures are reported inside the result so the model
+	// can see them; JSON-RPC errors are reserved for protocol problems.
 	if err != nil {
-		return map[string]interface{}{
-			"error": map[string]interface{}{
-				"code":    -32603,
-				"message": err.Error(),
+		return toolErrorResult(err, failures.Last(), debugRequested(handler, toolCall.Arguments)), nil
+	}
+
+	// Plain text results, such as tree listings, are passed through as is
+	if text, ok := result.(string); ok {
+		return toolResult(text, nil, false), nil
+	}
+
+	return toolResult(toJSON(result), result, false), nil
+}
+
+// toolErrorResult reports a failed tool call. When the failure came from the
+// Dropbox API, its X-Dropbox-Request-Id is always included so that it can be
+// quoted to Dropbox support; with debug the whole response is included.
+func toolErrorResult(err error, failure *dropbox.APIFailure, debug bool) map[string]interface{} {
+	switch {
+	case failure == nil:
+		return toolResult(err.Error(), nil, true)
+	case debug:
+		return toolResult(err.Error()+"\n\nDropbox API response: "+toJSON(failure), map[string]interface{}{
+			"error":            err.Error(),
+			"dropbox_response": failure,
+		}, true)
+	case failure.RequestID != "":
+		return toolResult(fmt.Sprintf("%s (Dropbox request ID: %s)", err, failure.RequestID), map[string]interface{}{
+			"error":      err.Error(),
+			"request_id": failure.RequestID,
+		}, true)
+	}
+	return toolResult(err.Error(), nil, true)
+}
+
+// debugRequested reports whether a tool call asked for debugging details,
+// either with its debug argument or through the debug setting.
+func debugRequested(handler *handlers.Handler, arguments json.RawMessage) bool {
+	var args struct {
+		Debug bool `json:"debug"`
+	}
+	// Arguments were already parsed by the tool, so errors are ignored
+	_ = json.Unmarshal(arguments, &args)
+	return args.Debug || handler.Debug()
+}
+
+// progressNotifier returns a ProgressFunc that sends notifications/progress
+// messages for the request that carried token.
+func progressNotifier(token json.RawMessage, notify func([]byte)) handlers.ProgressFunc {
+	return func(progress, total int, message string) {
+		output := marshalResponse(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "notifications/progress",
+			"params": map[string]interface{}{
+				"progressToken": token,
+				"progress":      progress,
+				"total":         total,
+				"message":       message,
 			},
+		})
+		if output != nil {
+			notify(output)
 		}
 	}
+}
 
-	return map[string]interface{}{
+// toolResult builds an MCP tool result. The text block is always present for
+// clients that only read content; structured is attached as structuredContent
+// for clients that parse machine-readable output.
+func toolResult(text string, structured interface{}, isError bool) map[string]interface{} {
+	result := map[string]interface{}{
 		"content": []map[string]interface{}{
 			{
 				"type": "text",
-				"text": toJSON(result),
+				"text": text,
 			},
 		},
+		"isError": isError,
+	}
+
+	if structured != nil {
+		if content := structuredContent(structured); content != nil {
+			result["structuredContent"] = content
+		}
+	}
+
+	return result
+}
+
+// structuredContent returns v as a JSON object, wrapping non-object values
+// (such as listings) under a "result" key as required by MCP.
+func structuredContent(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err == nil && object != nil {
+		return object
+	}
+
+	return map[string]interface{}{
+		"result": json.RawMessage(data),
 	}
 }
 
@@ -502,3 +1897,42 @@ func handleListResources() interface{} {
 		"resources": []interface{}{},
 	}
 }
+
+func handleListResourceTemplates(handler *handlers.Handler) interface{} {
+	return map[string]interface{}{
+		"resourceTemplates": handler.ResourceTemplates(),
+	}
+}
+
+func handleReadResource(handler *handlers.Handler, params json.RawMessage) (interface{}, *Error) {
+	var args struct {
+		URI string `json:"uri"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, &Error{
+			Code:    -32602,
+			Message: fmt.Sprintf("Invalid params: %v", err),
+		}
+	}
+
+	if args.URI == "" {
+		return nil, &Error{
+			Code:    -32602,
+			Message: "uri parameter is required",
+		}
+	}
+
+	ctx, cancel := timeoutContext(dropbox.EnvDuration("DROPBOX_MCP_TRANSFER_TIMEOUT", defaultTransferTimeout))
+	defer cancel()
+
+	result, err := handler.ReadResource(ctx, args.URI)
+	if err != nil {
+		return nil, &Error{
+			Code:    -32603,
+			Message: err.Error(),
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.ngs.io/dropbox-mcp-server/internal/dropbox"
+	"go.ngs.io/dropbox-mcp-server/internal/handlers"
+)
+
+// newTestHandler returns a handler backed by an empty config in a temporary
+// home directory, so that no real credentials are read or written.
+func newTestHandler(t *testing.T) *handlers.Handler {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, name := range []string{
+		"DROPBOX_CLIENT_ID", "DROPBOX_CLIENT_SECRET", "DROPBOX_REFRESH_TOKEN", "DROPBOX_ACCESS_TOKEN",
+		"DROPBOX_TEAM_MEMBER_ID", "DROPBOX_PATH_ROOT", "DROPBOX_BASE_PATH",
+		"DROPBOX_MCP_ENABLED_TOOLS", "DROPBOX_MCP_DISABLED_TOOLS", "DROPBOX_MCP_DEBUG",
+		"DROPBOX_MCP_UPLOAD_AUTORENAME", "DROPBOX_REDIRECT_URI", "DROPBOX_REDIRECT_HOST",
+		"DROPBOX_MCP_TMPDIR",
+	} {
+		t.Setenv(name, "")
+	}
+
+	handler, err := handlers.NewHandler()
+	if err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+	return handler
+}
+
+type transcriptEntry struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// loadTranscript reads a fixture of request lines and the responses expected
+// for them. A null response means the request must not be answered.
+func loadTranscript(t *testing.T, name string) []transcriptEntry {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []transcriptEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry transcriptEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid transcript line %s: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestServeStdioTranscript(t *testing.T) {
+	handler := newTestHandler(t)
+	entries := loadTranscript(t, "testdata/transcript.jsonl")
+
+	var input bytes.Buffer
+	var expected []json.RawMessage
+	for _, entry := range entries {
+		input.Write(entry.Request)
+		input.WriteByte('\n')
+		if string(entry.Response) != "null" {
+			expected = append(expected, entry.Response)
+		}
+	}
+
+	var output bytes.Buffer
+	if err := serveStdio(context.Background(), handler, &input, &output); err != nil {
+		t.Fatalf("serveStdio() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d responses, want %d:\n%s", len(lines), len(expected), output.String())
+	}
+
+	for i, line := range lines {
+		var got, want interface{}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("response %d is not JSON: %s", i, line)
+		}
+		if err := json.Unmarshal(expected[i], &want); err != nil {
+			t.Fatal(err)
+		}
+		if !matches(got, want) {
+			t.Errorf("response %d = %s, want it to match %s", i, line, expected[i])
+		}
+	}
+}
+
+func TestServeStdioLongLine(t *testing.T) {
+	handler := newTestHandler(t)
+
+	// Longer than the 1 MiB that a default-sized line scanner would accept
+	padding := strings.Repeat("x", 2<<20)
+	input := strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping","params":{"padding":"` + padding + `"}}` + "\n")
+
+	var output bytes.Buffer
+	if err := serveStdio(context.Background(), handler, input, &output); err != nil {
+		t.Fatalf("serveStdio() error = %v", err)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &response); err != nil {
+		t.Fatalf("response is not a single JSON message: %s", output.String())
+	}
+	if response["id"] != float64(7) || response["error"] != nil || response["result"] == nil {
+		t.Errorf("response = %s, want a result for request 7", output.String())
+	}
+}
+
+func TestHTTPOriginCheck(t *testing.T) {
+	server := newHTTPHandler(newTestHandler(t), []string{"https://app.example.com"})
+
+	for _, tt := range []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"http://localhost:3000", http.StatusOK},
+		{"http://127.0.0.1:8080", http.StatusOK},
+		{"http://[::1]", http.StatusOK},
+		{"https://app.example.com", http.StatusOK},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://localhost.evil.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Origin %q: status = %d, want %d", tt.origin, rec.Code, tt.want)
+		}
+	}
+}
+
+// matches reports whether got contains want: objects must have every key of
+// want with a matching value, while arrays and scalars must match in full.
+func matches(got, want interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range w {
+			if !matches(g[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !matches(g[i], w[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestToolsListMatchesDispatch(t *testing.T) {
+	handler := newTestHandler(t)
+
+	output := handleMessage(handler, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`), nil)
+	var resp struct {
+		Result struct {
+			Tools []ToolDefinition `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(output, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Result.Tools) == 0 {
+		t.Fatal("tools/list returned no tools")
+	}
+
+	// Every listed tool must be dispatched, and every dispatched tool listed
+	funcs := toolHandlerFuncs(handler)
+	listed := make(map[string]bool, len(resp.Result.Tools))
+	for _, tool := range resp.Result.Tools {
+		listed[tool.Name] = true
+		if _, ok := funcs[tool.Name]; !ok {
+			t.Errorf("%s is listed but has no handler", tool.Name)
+		}
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("%s: input schema type = %v, want object", tool.Name, tool.InputSchema["type"])
+		}
+	}
+	for name := range funcs {
+		if !listed[name] {
+			t.Errorf("%s has a handler but is not listed", name)
+		}
+	}
+}
+
+func TestWriteToolsJSON(t *testing.T) {
+	newTestHandler(t)
+	t.Setenv("DROPBOX_MCP_DISABLED_TOOLS", "dropbox_delete")
+
+	var buf bytes.Buffer
+	if err := writeToolsJSON(&buf); err != nil {
+		t.Fatalf("writeToolsJSON() error = %v", err)
+	}
+	var tools []ToolDefinition
+	if err := json.Unmarshal(buf.Bytes(), &tools); err != nil {
+		t.Fatalf("writeToolsJSON() wrote invalid JSON: %v", err)
+	}
+	if len(tools) != len(toolDefinitions())-1 {
+		t.Errorf("writeToolsJSON() wrote %d tools, want all but dropbox_delete", len(tools))
+	}
+	for _, tool := range tools {
+		if tool.Name == "dropbox_delete" {
+			t.Error("writeToolsJSON() listed the disabled dropbox_delete")
+		}
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		tool      string
+		arguments string
+		want      string
+	}{
+		{"dropbox_get_metadata", `{"path":"/notes.txt"}`, ""},
+		{"dropbox_get_metadata", `{"path":"/notes.txt","unknown":1}`, ""},
+		{"dropbox_get_metadata", `{"path":null}`, "path is required"},
+		{"dropbox_get_metadata", `{"path":42}`, "path must be a string, not a number"},
+		{"dropbox_get_metadata", `{"path":"/notes.txt","debug":"yes"}`, "debug must be a boolean, not a string"},
+		{"dropbox_get_revisions", `{"path":"/notes.txt","limit":1.5}`, "limit must be an integer, not a number"},
+		{"dropbox_get_revisions", `{"path":"/notes.txt","limit":101}`, "limit must be at most 100"},
+		{"dropbox_get_revisions", `{"path":"/notes.txt","mode":"name"}`, `mode must be one of "path", "id"`},
+		{"dropbox_move_batch", `{"entries":[{"from_path":"/a","to_path":1}]}`, "entries[0].to_path must be a string, not a number"},
+		{"dropbox_get_metadata", `["/notes.txt"]`, "arguments must be an object"},
+	}
+	for _, tt := range tests {
+		tool, ok := findTool(tt.tool)
+		if !ok {
+			t.Fatalf("findTool(%s) found no tool", tt.tool)
+		}
+		err := validateArguments(withDebugProperty(tool), json.RawMessage(tt.arguments))
+		if got := fmt.Sprint(err); (tt.want == "" && err != nil) || (tt.want != "" && got != tt.want) {
+			t.Errorf("validateArguments(%s, %s) = %v, want %q", tt.tool, tt.arguments, err, tt.want)
+		}
+	}
+}
+
+func TestToolErrorResultIncludesRequestID(t *testing.T) {
+	err := errors.New("failed to move: to/conflict/file/..")
+	failure := &dropbox.APIFailure{RequestID: "abc123", StatusCode: 409, Body: `{"error_summary": "to/conflict/file/.."}`}
+
+	result := toolErrorResult(err, failure, false)
+	text := result["content"].([]map[string]interface{})[0]["text"].(string)
+	if !strings.Contains(text, "abc123") || strings.Contains(text, "error_summary") {
+		t.Errorf("text = %q, want the request ID without the response body", text)
+	}
+	if result["isError"] != true {
+		t.Error("isError = false, want true")
+	}
+
+	result = toolErrorResult(err, failure, true)
+	text = result["content"].([]map[string]interface{})[0]["text"].(string)
+	if !strings.Contains(text, "abc123") || !strings.Contains(text, "error_summary") {
+		t.Errorf("debug text = %q, want the request ID and the response body", text)
+	}
+
+	result = toolErrorResult(err, nil, true)
+	if text := result["content"].([]map[string]interface{})[0]["text"].(string); text != err.Error() {
+		t.Errorf("text without an API failure = %q, want %q", text, err.Error())
+	}
+}
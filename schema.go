@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// inputSchema is the subset of JSON Schema that the tool input schemas use.
+type inputSchema struct {
+	Type       string                  `json:"type"`
+	Properties map[string]*inputSchema `json:"properties"`
+	Required   []string                `json:"required"`
+	Items      *inputSchema            `json:"items"`
+	Enum       []interface{}           `json:"enum"`
+	Minimum    *float64                `json:"minimum"`
+	Maximum    *float64                `json:"maximum"`
+}
+
+// findTool returns the definition of the named tool.
+func findTool(name string) (ToolDefinition, bool) {
+	for _, tool := range toolDefinitions() {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return ToolDefinition{}, false
+}
+
+// validateArguments checks the arguments of a tool call against the tool's
+// input schema, so that a wrong type or value is reported with the name of
+// the argument rather than as a decoding failure inside the handler.
+// Arguments the schema does not declare are left to the handler.
+func validateArguments(tool ToolDefinition, arguments json.RawMessage) error {
+	data, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return err
+	}
+	var schema inputSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(arguments, &value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return fmt.Errorf("arguments must be an object")
+	}
+	return schema.validate("", value)
+}
+
+func (s *inputSchema) validate(field string, value interface{}) error {
+	if err := s.validateType(field, value); err != nil {
+		return err
+	}
+
+	if len(s.Enum) > 0 && !s.allows(value) {
+		return fmt.Errorf("%s must be one of %s", field, s.enumList())
+	}
+
+	if number, ok := value.(float64); ok {
+		if s.Minimum != nil && number < *s.Minimum {
+			return fmt.Errorf("%s must be at least %v", field, *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fmt.Errorf("%s must be at most %v", field, *s.Maximum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(field, v)
+	case []interface{}:
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject checks the required and declared properties of an object.
+// A null property is treated as absent.
+func (s *inputSchema) validateObject(field string, object map[string]interface{}) error {
+	prefix := ""
+	if field != "" {
+		prefix = field + "."
+	}
+
+	for _, name := range s.Required {
+		if object[name] == nil {
+			return fmt.Errorf("%s%s is required", prefix, name)
+		}
+	}
+
+	for name, property := range s.Properties {
+		value, ok := object[name]
+		if !ok || value == nil {
+			continue
+		}
+		if err := property.validate(prefix+name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *inputSchema) validateType(field string, value interface{}) error {
+	var ok bool
+	switch s.Type {
+	case "string":
+		_, ok = value.(string)
+	case "boolean":
+		_, ok = value.(bool)
+	case "number":
+		_, ok = value.(float64)
+	case "integer":
+		var number float64
+		number, ok = value.(float64)
+		ok = ok && number == math.Trunc(number)
+	case "array":
+		_, ok = value.([]interface{})
+	case "object":
+		_, ok = value.(map[string]interface{})
+	default:
+		ok = true
+	}
+
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("%s must be %s %s, not %s", field, article(s.Type), s.Type, jsonType(value))
+}
+
+func (s *inputSchema) allows(value interface{}) bool {
+	for _, allowed := range s.Enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *inputSchema) enumList() string {
+	values := make([]string, 0, len(s.Enum))
+	for _, allowed := range s.Enum {
+		data, _ := json.Marshal(allowed)
+		values = append(values, string(data))
+	}
+	return strings.Join(values, ", ")
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return "null"
+}
+
+func article(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an"
+	}
+	return "a"
+}
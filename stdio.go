@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.ngs.io/dropbox-mcp-server/internal/handlers"
+)
+
+// serveStdio reads newline-delimited JSON-RPC messages from r and writes responses
+// to w until EOF or until ctx is canceled. Lines are read with a growing buffer
+// so that large requests, such as inline base64 uploads, are not limited to a
+// fixed size. A request that is already being handled when ctx is canceled is
+// allowed to finish and its response is written before serveStdio returns.
+func serveStdio(ctx context.Context, handler *handlers.Handler, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			// Messages are handled one at a time, so notifications sent
+			// while handling a request never interleave with responses
+			notify := func(output []byte) {
+				if _, err := fmt.Fprintln(w, string(output)); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to write notification: %v\n", err)
+				}
+			}
+			if output := handleMessage(handler, line, notify); output != nil {
+				if _, err := fmt.Fprintln(w, string(output)); err != nil {
+					return fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+	}
+}
@@ -1,3 +1,35 @@
 package main
 
-const VERSION = "0.1.0"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Build information, set with -ldflags "-X main.version=..." at release time.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+func handleVersion(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var args struct {
+		Verbose bool `json:"verbose"`
+	}
+
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"name":    "dropbox-mcp-server",
+		"version": version,
+	}
+	if args.Verbose {
+		result["commit"] = commit
+		result["date"] = date
+	}
+
+	return result, nil
+}